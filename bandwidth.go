@@ -0,0 +1,45 @@
+package simulator
+
+import (
+	"time"
+)
+
+// A BandwidthModel gives the data rate of a link, which determines how long it takes to transmit a packet over it.
+// The radio of a node is shared by all of its peers, so a node transmits or receives a single packet at a time.
+type BandwidthModel interface {
+	// DataRate returns the rate in bits per second at which a node at originCoord transmits to a node at targetCoord
+	DataRate(originCoord Coordinate, targetCoord Coordinate) float64
+}
+
+// A FloorBandwidthModel is a BandwidthModel which accounts for the floor slabs between the nodes.
+// The simulator calls DataRateThroughFloors instead of DataRate, with the loss in dB of the slabs
+// given by the floor attenuation of its options.
+type FloorBandwidthModel interface {
+	BandwidthModel
+	DataRateThroughFloors(originCoord Coordinate, targetCoord Coordinate, floorLoss float64) float64
+}
+
+// FixedBandwidth gives all links the same data rate
+type FixedBandwidth struct {
+	BitsPerSecond float64
+}
+
+func (b FixedBandwidth) DataRate(originCoord Coordinate, targetCoord Coordinate) float64 {
+	return b.BitsPerSecond
+}
+
+// dataRate returns the data rate of the bandwidth model between the coordinates, passing the floor loss to models that account for it
+func (s *Simulator) dataRate(bandwidth BandwidthModel, originCoord Coordinate, targetCoord Coordinate) float64 {
+	if floorBandwidth, ok := bandwidth.(FloorBandwidthModel); ok {
+		return floorBandwidth.DataRateThroughFloors(originCoord, targetCoord, s.floorLoss(originCoord, targetCoord))
+	}
+	return bandwidth.DataRate(originCoord, targetCoord)
+}
+
+// serializationTime returns the time it takes to transmit a packet of the given size at the given rate in bits per second
+func serializationTime(packetSize int, bitsPerSecond float64) time.Duration {
+	if bitsPerSecond <= 0 {
+		panic("bandwidth model returned a non-positive data rate")
+	}
+	return time.Duration(float64(packetSize*8) / bitsPerSecond * float64(time.Second))
+}
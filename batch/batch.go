@@ -0,0 +1,228 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// A Factory constructs a new simulation for the given seed.
+// Each simulation runs on its own goroutine, so simulations must not share random sources, loggers or nodes.
+type Factory func(seed int64) *simulator.Simulator
+
+// A MetricsLogger is a logger which reports metrics of a run, such as loggers.StatisticsLogger.
+// The metrics of all loggers of a simulation implementing this interface are collected when the run has terminated.
+type MetricsLogger interface {
+	Metrics() map[string]float64
+}
+
+type Options struct {
+	// Duration is the simulated time each run is updated for, before it is terminated
+	Duration time.Duration
+	// Timeout is the wall-clock time after which a run is stopped and counted as failed, or 0 for no limit
+	Timeout time.Duration
+	// Workers is the number of runs executed concurrently
+	Workers int
+	// ConfidenceLevel is the level of the reported confidence intervals, e.g. 0.95
+	ConfidenceLevel float64
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Duration:        60 * time.Second,
+		Timeout:         0,
+		Workers:         runtime.NumCPU(),
+		ConfidenceLevel: 0.95,
+	}
+}
+
+// RunResult is the outcome of a single run
+type RunResult struct {
+	Seed     int64
+	Metrics  map[string]float64
+	RealTime time.Duration
+	// Err is the error the simulation terminated with, or the value it panicked with
+	Err error
+}
+
+// Summary aggregates a single metric over all successful runs
+type Summary struct {
+	Metric string
+	N      int
+	Mean   float64
+	StdDev float64
+	// CILow and CIHigh bound the confidence interval of the mean, using the t-distribution
+	CILow  float64
+	CIHigh float64
+}
+
+type Result struct {
+	// Runs are ordered like the given seeds
+	Runs []RunResult
+	// Summaries are ordered by metric name
+	Summaries       []Summary
+	ConfidenceLevel float64
+}
+
+// Seeds returns count consecutive seeds starting from first
+func Seeds(first int64, count int) []int64 {
+	seeds := make([]int64, count)
+	for i := range seeds {
+		seeds[i] = first + int64(i)
+	}
+	return seeds
+}
+
+// Run executes a simulation for each of the seeds on a pool of workers, and aggregates the metrics of the runs.
+// Options may be nil, in which case DefaultOptions is used.
+func Run(factory Factory, seeds []int64, options *Options) *Result {
+	if options == nil {
+		options = DefaultOptions()
+	}
+	if options.Workers <= 0 {
+		panic("batch needs at least one worker")
+	}
+
+	runs := make([]RunResult, len(seeds))
+	runPool(len(seeds), options.Workers, func(i int) {
+		runs[i] = runOnce(factory, seeds[i], options)
+	})
+
+	return &Result{
+		Runs:            runs,
+		Summaries:       summarize(runs, options.ConfidenceLevel),
+		ConfidenceLevel: options.ConfidenceLevel,
+	}
+}
+
+// runPool calls run for each index from 0 to count on a pool of workers, and waits for all calls to return
+func runPool(count int, workers int, run func(i int)) {
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				run(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
+
+func runOnce(factory Factory, seed int64, options *Options) (result RunResult) {
+	result.Seed = seed
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("run with seed %d panicked: %v", seed, r)
+		}
+		result.RealTime = time.Since(start)
+	}()
+
+	sim := factory(seed)
+	run := sim.Run(context.Background(), &simulator.RunOptions{Until: options.Duration, Timeout: options.Timeout})
+	result.Err = run.Err
+	if run.Reason == simulator.StopTimeout {
+		result.Err = fmt.Errorf("run with seed %d timed out at %s simulated time", seed, run.Time)
+	}
+	// Hung runs are terminated like completed runs, such that their loggers are flushed
+	if sim.IsRunning() {
+		sim.Terminate()
+	}
+
+	result.Metrics = make(map[string]float64)
+	for _, logger := range sim.Loggers() {
+		if metricsLogger, ok := logger.(MetricsLogger); ok {
+			for metric, value := range metricsLogger.Metrics() {
+				result.Metrics[metric] = value
+			}
+		}
+	}
+
+	return result
+}
+
+func summarize(runs []RunResult, confidenceLevel float64) []Summary {
+	values := make(map[string][]float64)
+	for _, run := range runs {
+		if run.Err != nil {
+			continue
+		}
+		for metric, value := range run.Metrics {
+			values[metric] = append(values[metric], value)
+		}
+	}
+
+	metrics := make([]string, 0, len(values))
+	for metric := range values {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	summaries := make([]Summary, 0, len(metrics))
+	for _, metric := range metrics {
+		samples := values[metric]
+		mean, stdDev := meanStdDev(samples)
+		margin := confidenceMargin(stdDev, len(samples), confidenceLevel)
+		summaries = append(summaries, Summary{
+			Metric: metric,
+			N:      len(samples),
+			Mean:   mean,
+			StdDev: stdDev,
+			CILow:  mean - margin,
+			CIHigh: mean + margin,
+		})
+	}
+	return summaries
+}
+
+// Summary returns the summary of the given metric
+func (r *Result) Summary(metric string) (Summary, bool) {
+	for _, summary := range r.Summaries {
+		if summary.Metric == metric {
+			return summary, true
+		}
+	}
+	return Summary{}, false
+}
+
+// Failed returns the runs that terminated with an error
+func (r *Result) Failed() []RunResult {
+	failed := []RunResult{}
+	for _, run := range r.Runs {
+		if run.Err != nil {
+			failed = append(failed, run)
+		}
+	}
+	return failed
+}
+
+// Print writes the summaries as a table, followed by the errors of failed runs
+func (r *Result) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "metric\tn\tmean\tstddev\t%.0f%% ci\n", r.ConfidenceLevel*100)
+	for _, summary := range r.Summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%.4g\t%.4g\t[%.4g, %.4g]\n", summary.Metric, summary.N, summary.Mean, summary.StdDev, summary.CILow, summary.CIHigh)
+	}
+	tw.Flush()
+
+	for _, run := range r.Failed() {
+		fmt.Fprintf(w, "seed %d failed: %v\n", run.Seed, run.Err)
+	}
+}
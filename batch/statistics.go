@@ -0,0 +1,127 @@
+package batch
+
+import "math"
+
+// meanStdDev returns the mean and the sample standard deviation of the values
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+// confidenceMargin returns the half width of the confidence interval of the mean of n samples
+func confidenceMargin(stdDev float64, n int, confidenceLevel float64) float64 {
+	if n < 2 {
+		return math.NaN()
+	}
+	t := studentTQuantile(1-(1-confidenceLevel)/2, float64(n-1))
+	return t * stdDev / math.Sqrt(float64(n))
+}
+
+// studentTQuantile finds the t value with the given cumulative probability by bisection
+func studentTQuantile(p float64, df float64) float64 {
+	low, high := 0.0, 1.0
+	for studentTCDF(high, df) < p {
+		high *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if studentTCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// studentTCDF is the cumulative distribution function of the t-distribution for t >= 0
+func studentTCDF(t float64, df float64) float64 {
+	x := df / (df + t*t)
+	return 1 - 0.5*regularizedIncompleteBeta(x, df/2, 0.5)
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) using its continued fraction
+func regularizedIncompleteBeta(x float64, a float64, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly for x < (a+1)/(a+b+2), otherwise the symmetry relation is used
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(1-x, b, a)/b
+	}
+	return front * betaContinuedFraction(x, a, b) / a
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function using Lentz's method
+func betaContinuedFraction(x float64, a float64, b float64) float64 {
+	const epsilon = 1e-15
+	const tiny = 1e-300
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+
+	for m := 1.0; m < 300; m++ {
+		// Even step
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		result *= d * c
+
+		// Odd step
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return result
+}
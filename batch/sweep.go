@@ -0,0 +1,305 @@
+package batch
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/starling/device"
+)
+
+// Names of the parameters commonly swept over. Any name can be used, as long as the factory reads it.
+const (
+	BLERange          = "bleRange"
+	TransmissionDelay = "transmissionDelay"
+	NodeCount         = "nodeCount"
+	DropChance        = "dropChance"
+	LongTailAlpha     = "longTailAlpha"
+	LongTailXm        = "longTailXm"
+)
+
+// ProtocolOptionPrefix is the prefix of parameters that set a field of device.ProtocolOptions, e.g. "protocol.MaxRREQTTL"
+const ProtocolOptionPrefix = "protocol."
+
+// A Parameter is a named list of values to sweep over
+type Parameter struct {
+	Name   string
+	Values []interface{}
+}
+
+func Values(name string, values ...interface{}) Parameter {
+	return Parameter{Name: name, Values: values}
+}
+
+func Floats(name string, values ...float64) Parameter {
+	return Parameter{Name: name, Values: toInterfaces(values)}
+}
+
+func Ints(name string, values ...int) Parameter {
+	return Parameter{Name: name, Values: toInterfaces(values)}
+}
+
+func Durations(name string, values ...time.Duration) Parameter {
+	return Parameter{Name: name, Values: toInterfaces(values)}
+}
+
+func Bools(name string, values ...bool) Parameter {
+	return Parameter{Name: name, Values: toInterfaces(values)}
+}
+
+func toInterfaces[T any](values []T) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// Params assigns a value to each parameter of a sweep
+type Params map[string]interface{}
+
+func (p Params) value(name string) interface{} {
+	value, found := p[name]
+	if !found {
+		panic(fmt.Sprintf("sweep parameter %s is not defined", name))
+	}
+	return value
+}
+
+func (p Params) Float(name string) float64 {
+	switch value := p.value(name).(type) {
+	case float64:
+		return value
+	case int:
+		return float64(value)
+	default:
+		panic(fmt.Sprintf("sweep parameter %s is not a number", name))
+	}
+}
+
+func (p Params) Int(name string) int {
+	value, ok := p.value(name).(int)
+	if !ok {
+		panic(fmt.Sprintf("sweep parameter %s is not an int", name))
+	}
+	return value
+}
+
+func (p Params) Duration(name string) time.Duration {
+	value, ok := p.value(name).(time.Duration)
+	if !ok {
+		panic(fmt.Sprintf("sweep parameter %s is not a duration", name))
+	}
+	return value
+}
+
+func (p Params) Bool(name string) bool {
+	value, ok := p.value(name).(bool)
+	if !ok {
+		panic(fmt.Sprintf("sweep parameter %s is not a bool", name))
+	}
+	return value
+}
+
+// FloatOr returns the parameter if it is swept over, otherwise the given default
+func (p Params) FloatOr(name string, def float64) float64 {
+	if _, found := p[name]; !found {
+		return def
+	}
+	return p.Float(name)
+}
+
+// IntOr returns the parameter if it is swept over, otherwise the given default
+func (p Params) IntOr(name string, def int) int {
+	if _, found := p[name]; !found {
+		return def
+	}
+	return p.Int(name)
+}
+
+// DurationOr returns the parameter if it is swept over, otherwise the given default
+func (p Params) DurationOr(name string, def time.Duration) time.Duration {
+	if _, found := p[name]; !found {
+		return def
+	}
+	return p.Duration(name)
+}
+
+// ProtocolOptions returns a copy of base, with the fields given by parameters prefixed by ProtocolOptionPrefix overwritten.
+// If base is nil, device.DefaultProtocolOptions is used.
+func (p Params) ProtocolOptions(base *device.ProtocolOptions) *device.ProtocolOptions {
+	if base == nil {
+		base = device.DefaultProtocolOptions()
+	}
+	options := *base
+
+	fields := reflect.ValueOf(&options).Elem()
+	for name, value := range p {
+		fieldName, isProtocolOption := strings.CutPrefix(name, ProtocolOptionPrefix)
+		if !isProtocolOption {
+			continue
+		}
+
+		field := fields.FieldByName(fieldName)
+		if !field.IsValid() {
+			panic(fmt.Sprintf("protocol options have no field %s", fieldName))
+		}
+		v := reflect.ValueOf(value)
+		if !v.Type().ConvertibleTo(field.Type()) {
+			panic(fmt.Sprintf("sweep parameter %s of type %s cannot be assigned to protocol option of type %s", name, v.Type(), field.Type()))
+		}
+		field.Set(v.Convert(field.Type()))
+	}
+
+	return &options
+}
+
+func (p Params) format(name string) string {
+	return fmt.Sprint(p[name])
+}
+
+// A SweepFactory constructs a new simulation for the given parameters and seed
+type SweepFactory func(params Params, seed int64) *simulator.Simulator
+
+// A SweepPoint is the aggregated result of all seeds for one combination of parameters
+type SweepPoint struct {
+	Params Params
+	Result *Result
+}
+
+type SweepResult struct {
+	// Parameters are the names of the swept parameters, in the order they were given
+	Parameters []string
+	// Points contains the combinations of parameters, with the first parameter varying the slowest
+	Points []SweepPoint
+}
+
+// Sweep runs each seed for every combination of the parameter values.
+// All runs share a single pool of workers. Options may be nil, in which case DefaultOptions is used.
+func Sweep(factory SweepFactory, parameters []Parameter, seeds []int64, options *Options) *SweepResult {
+	if options == nil {
+		options = DefaultOptions()
+	}
+	if options.Workers <= 0 {
+		panic("sweep needs at least one worker")
+	}
+
+	combinations := []Params{{}}
+	names := []string{}
+	for _, parameter := range parameters {
+		if len(parameter.Values) == 0 {
+			panic(fmt.Sprintf("sweep parameter %s has no values", parameter.Name))
+		}
+		names = append(names, parameter.Name)
+
+		next := []Params{}
+		for _, params := range combinations {
+			for _, value := range parameter.Values {
+				extended := Params{}
+				for name, v := range params {
+					extended[name] = v
+				}
+				extended[parameter.Name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+
+	runs := make([][]RunResult, len(combinations))
+	for i := range runs {
+		runs[i] = make([]RunResult, len(seeds))
+	}
+
+	runPool(len(combinations)*len(seeds), options.Workers, func(i int) {
+		params := combinations[i/len(seeds)]
+		seed := seeds[i%len(seeds)]
+		runs[i/len(seeds)][i%len(seeds)] = runOnce(func(seed int64) *simulator.Simulator {
+			return factory(params, seed)
+		}, seed, options)
+	})
+
+	points := make([]SweepPoint, len(combinations))
+	for i, params := range combinations {
+		points[i] = SweepPoint{
+			Params: params,
+			Result: &Result{
+				Runs:            runs[i],
+				Summaries:       summarize(runs[i], options.ConfidenceLevel),
+				ConfidenceLevel: options.ConfidenceLevel,
+			},
+		}
+	}
+
+	return &SweepResult{
+		Parameters: names,
+		Points:     points,
+	}
+}
+
+// Print writes a table with a row for each combination of parameters, and the mean and confidence interval of the given metrics
+func (r *SweepResult) Print(w io.Writer, metrics ...string) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprint(tw, strings.Join(r.Parameters, "\t"))
+	for _, metric := range metrics {
+		fmt.Fprintf(tw, "\t%s", metric)
+	}
+	fmt.Fprint(tw, "\tfailed\n")
+
+	for _, point := range r.Points {
+		for i, name := range r.Parameters {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, point.Params.format(name))
+		}
+		for _, metric := range metrics {
+			summary, found := point.Result.Summary(metric)
+			if !found {
+				fmt.Fprint(tw, "\t-")
+				continue
+			}
+			fmt.Fprintf(tw, "\t%.4g ± %.2g", summary.Mean, (summary.CIHigh-summary.CILow)/2)
+		}
+		fmt.Fprintf(tw, "\t%d\n", len(point.Result.Failed()))
+	}
+	tw.Flush()
+}
+
+// WriteCSV writes a row for each combination of parameters and metric
+func (r *SweepResult) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{}, r.Parameters...)
+	header = append(header, "metric", "n", "mean", "stddev", "ci_low", "ci_high")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	for _, point := range r.Points {
+		for _, summary := range point.Result.Summaries {
+			row := []string{}
+			for _, name := range r.Parameters {
+				row = append(row, point.Params.format(name))
+			}
+			row = append(row, summary.Metric, strconv.Itoa(summary.N), formatFloat(summary.Mean), formatFloat(summary.StdDev), formatFloat(summary.CILow), formatFloat(summary.CIHigh))
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,78 @@
+package bridge_node
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// A MessageType is the first byte of each datagram exchanged with the external process
+type MessageType byte
+
+const (
+	// MessageStart is sent when the node is added to the simulation, the ID is that of the node itself
+	MessageStart MessageType = iota + 1
+	// MessageRestart is sent when the node restarts after a crash, the ID is that of the node itself,
+	// and the payload is a single byte which is 1 if the persisted state of the node survived the crash
+	MessageRestart
+	// MessageTerminate is sent when the node is removed or the simulation terminates, the ID is that of the node itself
+	MessageTerminate
+	// MessageConnect is sent when the node connects to the peer with the ID
+	MessageConnect
+	// MessageDisconnect is sent when the node disconnects from the peer with the ID
+	MessageDisconnect
+	// MessagePacket carries a packet in the payload. It is sent when the node receives a packet from the peer with the ID,
+	// and the external process sends it to have the node send a packet to the peer with the ID.
+	MessagePacket
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case MessageStart:
+		return "start"
+	case MessageRestart:
+		return "restart"
+	case MessageTerminate:
+		return "terminate"
+	case MessageConnect:
+		return "connect"
+	case MessageDisconnect:
+		return "disconnect"
+	case MessagePacket:
+		return "packet"
+	default:
+		return fmt.Sprintf("MessageType(%d)", byte(t))
+	}
+}
+
+// headerSize is the size of the type and the big-endian node ID which precede the payload of each message
+const headerSize = 1 + 8
+
+// A Message is a single datagram exchanged with the external process
+type Message struct {
+	Type    MessageType
+	ID      simulator.NodeID
+	Payload []byte
+}
+
+// Encode returns the datagram of the message
+func (m Message) Encode() []byte {
+	data := make([]byte, headerSize, headerSize+len(m.Payload))
+	data[0] = byte(m.Type)
+	binary.BigEndian.PutUint64(data[1:headerSize], uint64(m.ID))
+	return append(data, m.Payload...)
+}
+
+// DecodeMessage decodes a datagram, the payload of the message refers to the datagram
+func DecodeMessage(data []byte) (Message, error) {
+	if len(data) < headerSize {
+		return Message{}, errors.New("message is too short")
+	}
+	return Message{
+		Type:    MessageType(data[0]),
+		ID:      simulator.NodeID(binary.BigEndian.Uint64(data[1:headerSize])),
+		Payload: data[headerSize:],
+	}, nil
+}
@@ -0,0 +1,230 @@
+// Package bridge_node connects a node of the simulation to an external process over a local UDP or Unix datagram socket,
+// such that a real Starling build can take part in a simulated crowd. The events of the node are forwarded to the
+// external process as messages, and the packets it sends back are sent to the peers of the node, see Message.
+//
+// The simulator is not paced by the external process, so the simulation should be run in real time, see simulator.RunOptions.Speed.
+package bridge_node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+type Options struct {
+	// PollInterval is the simulated time between each time the node handles the messages of the external process
+	PollInterval time.Duration
+	// MaxMessageSize is the size of the largest datagram that can be received from the external process
+	MaxMessageSize int
+	// QueueSize is the number of messages from the external process that can wait to be handled, further messages are dropped
+	QueueSize int
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		PollInterval:   10 * time.Millisecond,
+		MaxMessageSize: 65536,
+		QueueSize:      1024,
+	}
+}
+
+type Node struct {
+	id                   simulator.NodeID
+	sim                  *simulator.NodeArguments
+	transmissionBehavior simulator.TransmissionBehavior
+	options              *Options
+	peers                map[simulator.NodeID]simulator.Peer
+
+	conn      net.PacketConn
+	remote    net.Addr
+	incoming  chan Message
+	closed    atomic.Bool // closed is set by Close, which may be called from outside the simulation
+	closeOnce sync.Once
+}
+
+// NewNode creates a node which listens on the local address and forwards its events to the remote address.
+// The network is either udp or unixgram. If options is nil, DefaultOptions is used.
+func NewNode(id simulator.NodeID, network string, localAddress string, remoteAddress string, transmissionBehavior simulator.TransmissionBehavior, options *Options) (*Node, error) {
+	if options == nil {
+		options = DefaultOptions()
+	}
+	if options.PollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if options.QueueSize <= 0 {
+		return nil, errors.New("queue size must be positive")
+	}
+	if options.MaxMessageSize < headerSize {
+		return nil, fmt.Errorf("max message size must be at least %d", headerSize)
+	}
+
+	remote, err := resolveAddress(network, remoteAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket(network, localAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{
+		id:                   id,
+		sim:                  nil,
+		transmissionBehavior: transmissionBehavior,
+		options:              options,
+		peers:                make(map[simulator.NodeID]simulator.Peer),
+		conn:                 conn,
+		remote:               remote,
+		incoming:             make(chan Message, options.QueueSize),
+	}
+	go node.receive()
+	return node, nil
+}
+
+func resolveAddress(network string, address string) (net.Addr, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+		return net.ResolveUDPAddr(network, address)
+	case "unixgram":
+		return net.ResolveUnixAddr(network, address)
+	default:
+		return nil, fmt.Errorf("unsupported network %s, it must be udp or unixgram", network)
+	}
+}
+
+// LocalAddress returns the address that the external process sends its messages to
+func (n *Node) LocalAddress() net.Addr {
+	return n.conn.LocalAddr()
+}
+
+// receive reads the messages of the external process until the socket is closed.
+// It runs in its own goroutine, so the messages are handed to the simulation through the incoming queue.
+func (n *Node) receive() {
+	buffer := make([]byte, n.options.MaxMessageSize)
+	for {
+		size, _, err := n.conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		message, err := DecodeMessage(append([]byte(nil), buffer[:size]...))
+		if err != nil {
+			continue
+		}
+		select {
+		case n.incoming <- message:
+		default:
+		}
+	}
+}
+
+// poll handles the messages that the external process has sent since the last poll
+func (n *Node) poll() {
+	if n.closed.Load() {
+		return
+	}
+
+	for {
+		select {
+		case message := <-n.incoming:
+			n.handle(message)
+		default:
+			n.sim.DelayBy(n.poll, n.options.PollInterval)
+			return
+		}
+	}
+}
+
+func (n *Node) handle(message Message) {
+	if message.Type != MessagePacket {
+		n.sim.Log(fmt.Sprintf("bridge:%d:unexpected_message:%s", n.id, message.Type))
+		return
+	}
+	peer, found := n.peers[message.ID]
+	if !found {
+		n.sim.Log(fmt.Sprintf("bridge:%d:unknown_peer:%d", n.id, message.ID))
+		return
+	}
+	peer.SendPacket(message.Payload)
+}
+
+func (n *Node) send(message Message) {
+	if n.closed.Load() {
+		return
+	}
+	if _, err := n.conn.WriteTo(message.Encode(), n.remote); err != nil {
+		n.log(fmt.Sprintf("bridge:%d:send_failed:%s:%v", n.id, message.Type, err))
+	}
+}
+
+// log logs the message through the simulator, unless the node has not been started
+func (n *Node) log(message string) {
+	if n.sim != nil {
+		n.sim.Log(message)
+	}
+}
+
+// Close closes the socket of the node, which then stops forwarding its events
+func (n *Node) Close() error {
+	var err error
+	n.closeOnce.Do(func() {
+		n.closed.Store(true)
+		err = n.conn.Close()
+	})
+	return err
+}
+
+func (n *Node) OnConnect(peer simulator.Peer, id simulator.NodeID) {
+	n.peers[id] = peer
+	n.send(Message{Type: MessageConnect, ID: id})
+}
+
+func (n *Node) OnDisconnect(peer simulator.Peer, id simulator.NodeID) {
+	delete(n.peers, id)
+	n.send(Message{Type: MessageDisconnect, ID: id})
+}
+
+func (n *Node) OnReceivePacket(peer simulator.Peer, packet []byte, id simulator.NodeID) {
+	n.send(Message{Type: MessagePacket, ID: id, Payload: packet})
+}
+
+func (n *Node) OnStart(sim simulator.NodeArguments) {
+	n.sim = &sim
+	n.peers = make(map[simulator.NodeID]simulator.Peer)
+
+	if sim.Restarts > 0 {
+		persisted := byte(0)
+		if sim.PersistedState {
+			persisted = 1
+		}
+		n.send(Message{Type: MessageRestart, ID: n.id, Payload: []byte{persisted}})
+	} else {
+		n.send(Message{Type: MessageStart, ID: n.id})
+	}
+
+	// The timers of a crashed node are dropped, so polling is started again when it restarts
+	sim.DelayBy(n.poll, n.options.PollInterval)
+}
+
+func (n *Node) ID() simulator.NodeID {
+	return n.id
+}
+
+func (n *Node) OnTerminate() {
+	n.send(Message{Type: MessageTerminate, ID: n.id})
+	if err := n.Close(); err != nil {
+		n.log(fmt.Sprintf("bridge:%d:close_failed:%v", n.id, err))
+	}
+}
+
+func (n *Node) TransmissionBehavior() simulator.TransmissionBehavior {
+	return n.transmissionBehavior
+}
@@ -0,0 +1,709 @@
+package simulator
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// A Snapshotter can save and restore its internal state as part of a simulator checkpoint.
+// Movement profiles with internal state should implement it in order to be resumed correctly.
+type Snapshotter interface {
+	Snapshot() ([]byte, error)
+	RestoreSnapshot(snapshot []byte) error
+}
+
+// A NodeSnapshotter is a Node that can save and restore its internal state as part of a simulator checkpoint.
+// OnRestore is called instead of OnStart when a node is restored from a checkpoint, with the connections
+// that the node had when the checkpoint was taken, and OnConnect is not called for them.
+// Timers started with DelayBy cannot be saved, so the node is responsible for starting them again.
+type NodeSnapshotter interface {
+	Snapshot() ([]byte, error)
+	OnRestore(sim NodeArguments, snapshot []byte, connections []Connection) error
+}
+
+// A Connection is a connection to a peer that a node has when it is restored from a checkpoint
+type Connection struct {
+	Peer Peer
+	ID   NodeID
+}
+
+type checkpoint struct {
+	Time                  time.Duration
+	CurrentSequenceNumber int64
+	RandomSources         []randomSourceCheckpoint
+	Nodes                 []nodeCheckpoint
+	NodeOrder             []int
+	Regions               map[RegionCoord][]int
+	Peers                 []peerCheckpoint
+	Discoveries           []discoveryCheckpoint
+	Declined              [][2]int
+	Drops                 []dropCheckpoint
+	Partitions            []int
+	Events                []eventCheckpoint
+}
+
+type randomSourceCheckpoint struct {
+	Seed  int64
+	Draws uint64
+}
+
+type nodeCheckpoint struct {
+	Added               bool
+	Removed             bool
+	Crashed             bool
+	Restarts            int
+	NodeID              NodeID
+	Coords              Coordinate
+	MovementInstruction MovementInstruction
+	SegmentStart        Coordinate
+	SegmentStartTime    time.Duration
+	SegmentEndTime      time.Duration
+	CurBufferCount      int
+	LastMessageSent     time.Duration
+	LastMessageReceived time.Duration
+	Data                map[string][]byte
+	Neighbours          []int
+	ConnectedAt         []time.Duration
+	Movement            []byte
+	Node                []byte
+	Energy              *energyCheckpoint
+}
+
+type energyCheckpoint struct {
+	Usage       EnergyUsage
+	UpdatedAt   time.Duration
+	Started     bool
+	Connections int
+	Scanning    bool
+	Depleted    bool
+	DepletedAt  time.Duration
+}
+
+type peerCheckpoint struct {
+	A     int
+	B     int
+	DataA map[string][]byte
+	DataB map[string][]byte
+}
+
+type discoveryCheckpoint struct {
+	A        int
+	B        int
+	Start    time.Duration
+	Time     time.Duration
+	Fail     bool
+	Lifetime time.Duration
+}
+
+type dropCheckpoint struct {
+	A    int
+	B    int
+	Time time.Duration
+}
+
+type eventCheckpoint struct {
+	EventType       EventType
+	Time            time.Duration
+	SequenceNumber  int64
+	NodeA           int
+	NodeB           int
+	NodeID          NodeID
+	ShouldBeDropped bool
+	Delay           time.Duration
+	Packet          []byte
+	Queued          bool // Queued is set for packets waiting behind the head of a buffer, which are not in the event queue
+	Err             string
+	Duplicate       bool
+	Intercepted     bool
+	Partition       int
+	Random          bool
+}
+
+// Checkpoint writes the state of the simulator to w, such that it can be resumed later using Restore.
+//
+// The checkpoint contains the event queue, node positions and movement, peers, buffers and data maps.
+// Nodes and movement profiles are included through the NodeSnapshotter and Snapshotter interfaces.
+// Pending DelayBy timers cannot be serialized and are not part of the checkpoint.
+//
+// The state of the random sources tracked with TrackRandomSource is saved, so taking a checkpoint does not affect the run.
+// Other random sources, such as those created with rand.NewSource and held by the simulator, movement profiles or
+// transmission behaviors, cannot be saved, and a run restored from the checkpoint is only reproducible if they are tracked.
+func (s *Simulator) Checkpoint(w io.Writer) error {
+	nodeIndex := make(map[*InternalNode]int)
+	for i, iNode := range s.registeredNodes {
+		nodeIndex[iNode] = i
+	}
+
+	cp := checkpoint{
+		Time:                  s.time,
+		CurrentSequenceNumber: s.currentSequenceNumber,
+		RandomSources:         []randomSourceCheckpoint{},
+		Nodes:                 []nodeCheckpoint{},
+		NodeOrder:             []int{},
+		Regions:               make(map[RegionCoord][]int),
+		Peers:                 []peerCheckpoint{},
+		Discoveries:           []discoveryCheckpoint{},
+		Declined:              [][2]int{},
+		Drops:                 []dropCheckpoint{},
+		Events:                []eventCheckpoint{},
+	}
+
+	for _, source := range s.randomSources {
+		cp.RandomSources = append(cp.RandomSources, randomSourceCheckpoint{Seed: source.seed, Draws: source.draws})
+	}
+
+	added := make(map[*InternalNode]bool)
+	for _, iNode := range s.nodes {
+		added[iNode] = true
+		cp.NodeOrder = append(cp.NodeOrder, nodeIndex[iNode])
+	}
+
+	for coord, region := range s.regionMap.regionMap {
+		indices := []int{}
+		for _, iNode := range region.Nodes {
+			indices = append(indices, nodeIndex[iNode])
+		}
+		cp.Regions[coord] = indices
+	}
+
+	for _, iNode := range s.registeredNodes {
+		nodeCp := nodeCheckpoint{
+			Added:               added[iNode],
+			Removed:             iNode.removed,
+			Crashed:             iNode.crashed,
+			Restarts:            iNode.restarts,
+			NodeID:              iNode.nodeID,
+			Coords:              iNode.coords,
+			MovementInstruction: iNode.movementInstruction,
+			SegmentStart:        iNode.segmentStart,
+			SegmentStartTime:    iNode.segmentStartTime,
+			SegmentEndTime:      iNode.segmentEndTime,
+			CurBufferCount:      iNode.curBufferCount,
+			LastMessageSent:     iNode.lastMessageSent,
+			LastMessageReceived: iNode.lastMessageReceived,
+			Data:                s.encodeData(iNode.data),
+			Neighbours:          []int{},
+		}
+
+		if e := iNode.energy; e != nil {
+			nodeCp.Energy = &energyCheckpoint{
+				Usage:       e.usage,
+				UpdatedAt:   e.updatedAt,
+				Started:     e.started,
+				Connections: e.connections,
+				Scanning:    e.scanning,
+				Depleted:    e.depleted,
+				DepletedAt:  e.depletedAt,
+			}
+		}
+
+		for _, peer := range iNode.peers {
+			nodeCp.Neighbours = append(nodeCp.Neighbours, nodeIndex[peer.target])
+		}
+		slices.Sort(nodeCp.Neighbours)
+		for _, j := range nodeCp.Neighbours {
+			nodeCp.ConnectedAt = append(nodeCp.ConnectedAt, iNode.connectedAt[s.registeredNodes[j].internalID])
+		}
+
+		if snapshotter, ok := iNode.nodeMovement.(Snapshotter); ok {
+			snapshot, err := snapshotter.Snapshot()
+			if err != nil {
+				return fmt.Errorf("failed to snapshot movement of node %d: %w", iNode.nodeID, err)
+			}
+			nodeCp.Movement = snapshot
+		}
+
+		if snapshotter, ok := iNode.node.(NodeSnapshotter); ok && nodeCp.Added {
+			snapshot, err := snapshotter.Snapshot()
+			if err != nil {
+				return fmt.Errorf("failed to snapshot node %d: %w", iNode.nodeID, err)
+			}
+			nodeCp.Node = snapshot
+		}
+
+		cp.Nodes = append(cp.Nodes, nodeCp)
+	}
+
+	for _, peer := range s.peers.peers {
+		cp.Peers = append(cp.Peers, peerCheckpoint{
+			A:     nodeIndex[peer.a],
+			B:     nodeIndex[peer.b],
+			DataA: s.encodeData(peer.dataA),
+			DataB: s.encodeData(peer.dataB),
+		})
+	}
+
+	for _, d := range s.discoveries {
+		cp.Discoveries = append(cp.Discoveries, discoveryCheckpoint{
+			A:        nodeIndex[d.nodeA],
+			B:        nodeIndex[d.nodeB],
+			Start:    d.start,
+			Time:     d.time,
+			Fail:     d.fail,
+			Lifetime: d.lifetime,
+		})
+	}
+
+	for _, drop := range s.drops {
+		cp.Drops = append(cp.Drops, dropCheckpoint{
+			A:    nodeIndex[drop.nodeA],
+			B:    nodeIndex[drop.nodeB],
+			Time: drop.Time(),
+		})
+	}
+
+	for _, p := range s.regionMap.partitions {
+		cp.Partitions = append(cp.Partitions, p.index)
+	}
+
+	for _, nodes := range s.declined {
+		cp.Declined = append(cp.Declined, [2]int{nodeIndex[nodes[0]], nodeIndex[nodes[1]]})
+	}
+
+	for _, e := range *s.eventQueue {
+		if e.base().cancelled {
+			continue
+		}
+
+		eventCp := eventCheckpoint{
+			EventType:      e.EventType(),
+			Time:           e.Time(),
+			SequenceNumber: e.SequenceNumber(),
+			Duplicate:      e.base().duplicate,
+			Intercepted:    e.base().intercepted,
+		}
+
+		switch e.EventType() {
+		case TIMESTEP:
+		case MOVEMENT:
+			eventCp.NodeA = nodeIndex[e.(*MovementEvent).node]
+		case CONNECT:
+			connectEvent := e.(*ConnectEvent)
+			eventCp.NodeA = nodeIndex[connectEvent.nodeA]
+			eventCp.NodeB = nodeIndex[connectEvent.nodeB]
+		case DISCONNECT:
+			disconnectEvent := e.(*DisconnectEvent)
+			eventCp.NodeA = nodeIndex[disconnectEvent.nodeA]
+			eventCp.NodeB = nodeIndex[disconnectEvent.nodeB]
+		case ADD_NODE:
+			eventCp.NodeA = nodeIndex[e.(*AddNodeEvent).node]
+		case RM_NODE:
+			eventCp.NodeA = nodeIndex[e.(*RemoveNodeEvent).node]
+		case SEND_MSG:
+			eventCp.setSendEvent(e.(*SendEvent), nodeIndex)
+		case RCV_MSG:
+			receiveEvent := e.(*ReceiveEvent)
+			eventCp.NodeA = nodeIndex[receiveEvent.origin]
+			eventCp.NodeB = nodeIndex[receiveEvent.target]
+			eventCp.NodeID = receiveEvent.originNodeID
+			eventCp.Packet = receiveEvent.packet
+		case PARTITION:
+			eventCp.Partition = e.(*PartitionEvent).partition.index
+		case HEAL:
+			eventCp.Partition = e.(*HealEvent).partition.index
+		case CRASH:
+			crashEvent := e.(*CrashEvent)
+			eventCp.NodeA = nodeIndex[crashEvent.node]
+			eventCp.Delay = crashEvent.downtime
+			eventCp.Random = crashEvent.random
+		case RESTART:
+			eventCp.NodeA = nodeIndex[e.(*RestartEvent).node]
+		case DEPLETE:
+			eventCp.NodeA = nodeIndex[e.(*DepleteEvent).node]
+		case TERMINATE:
+			terminateEvent := e.(*TerminateEvent)
+			if terminateEvent.err != nil {
+				eventCp.Err = terminateEvent.err.Error()
+			}
+		case DELAY:
+			s.logDebug(fmt.Sprintf("simulator:checkpoint:skip_delay:%d", e.(*DelayEvent).node.nodeID))
+			continue
+		default:
+			return fmt.Errorf("cannot checkpoint event of type %s", e.EventType())
+		}
+
+		cp.Events = append(cp.Events, eventCp)
+	}
+
+	// The packets behind the head of each buffer are written after the events, such that they are restored in order behind it
+	for _, iNode := range s.nodes {
+		for i, sendEvent := range iNode.queue {
+			if i == 0 {
+				continue
+			}
+			eventCp := eventCheckpoint{
+				EventType:      SEND_MSG,
+				SequenceNumber: sendEvent.SequenceNumber(),
+				Queued:         true,
+			}
+			eventCp.setSendEvent(sendEvent, nodeIndex)
+			cp.Events = append(cp.Events, eventCp)
+		}
+	}
+
+	return gob.NewEncoder(w).Encode(cp)
+}
+
+func (cp *eventCheckpoint) setSendEvent(sendEvent *SendEvent, nodeIndex map[*InternalNode]int) {
+	cp.NodeA = nodeIndex[sendEvent.origin]
+	cp.NodeB = nodeIndex[sendEvent.target]
+	cp.NodeID = sendEvent.targetNodeID
+	cp.ShouldBeDropped = sendEvent.shouldBeDropped
+	cp.Delay = sendEvent.delay
+	cp.Packet = sendEvent.packet
+}
+
+// Restore loads a checkpoint written by Checkpoint.
+// The simulator must be constructed in the same way as the one the checkpoint was taken from,
+// with the same nodes added in the same order, and must not have been started yet.
+func (s *Simulator) Restore(r io.Reader) error {
+	if s.isRunning {
+		return errors.New("cannot restore a checkpoint into a running simulator")
+	}
+
+	var cp checkpoint
+	if err := gob.NewDecoder(r).Decode(&cp); err != nil {
+		return err
+	}
+
+	if len(cp.Nodes) != len(s.registeredNodes) {
+		return fmt.Errorf("checkpoint contains %d nodes, but %d nodes have been added to the simulator", len(cp.Nodes), len(s.registeredNodes))
+	}
+
+	if len(cp.RandomSources) != len(s.randomSources) {
+		return fmt.Errorf("checkpoint contains %d random sources, but %d random sources are tracked by the simulator", len(cp.RandomSources), len(s.randomSources))
+	}
+
+	s.time = cp.Time
+	s.currentSequenceNumber = cp.CurrentSequenceNumber
+	for i, sourceCp := range cp.RandomSources {
+		s.randomSources[i].restore(sourceCp.Seed, sourceCp.Draws)
+	}
+	s.nodes = []*InternalNode{}
+	peers := []InternalPeer{}
+	*s.eventQueue = (*s.eventQueue)[:0]
+
+	for i, nodeCp := range cp.Nodes {
+		iNode := s.registeredNodes[i]
+		iNode.removed = nodeCp.Removed
+		iNode.crashed = nodeCp.Crashed
+		iNode.restarts = nodeCp.Restarts
+		iNode.nextCrash = nil
+		if (iNode.energy == nil) != (nodeCp.Energy == nil) {
+			return fmt.Errorf("energy profile of node %d does not match the checkpoint", iNode.nodeID)
+		}
+		if e := nodeCp.Energy; e != nil {
+			iNode.energy = &energyState{
+				profile:     iNode.energy.profile,
+				usage:       e.Usage,
+				updatedAt:   e.UpdatedAt,
+				started:     e.Started,
+				connections: e.Connections,
+				scanning:    e.Scanning,
+				depleted:    e.Depleted,
+				depletedAt:  e.DepletedAt,
+			}
+		}
+		iNode.nodeID = nodeCp.NodeID
+		iNode.coords = nodeCp.Coords
+		iNode.dirty = true
+		iNode.movementInstruction = nodeCp.MovementInstruction
+		iNode.segmentStart = nodeCp.SegmentStart
+		iNode.segmentStartTime = nodeCp.SegmentStartTime
+		iNode.segmentEndTime = nodeCp.SegmentEndTime
+		iNode.curBufferCount = nodeCp.CurBufferCount
+		iNode.lastMessageSent = nodeCp.LastMessageSent
+		iNode.lastMessageReceived = nodeCp.LastMessageReceived
+		iNode.data = s.decodeData(nodeCp.Data)
+		iNode.peers = make(map[InternalID]Peer)
+		iNode.connectedAt = make(map[InternalID]time.Duration)
+
+		if nodeCp.Movement != nil {
+			snapshotter, ok := iNode.nodeMovement.(Snapshotter)
+			if !ok {
+				return fmt.Errorf("movement of node %d does not implement Snapshotter", iNode.nodeID)
+			}
+			if err := snapshotter.RestoreSnapshot(nodeCp.Movement); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, i := range cp.NodeOrder {
+		s.nodes = append(s.nodes, s.registeredNodes[i])
+	}
+
+	s.regionMap.regionMap = make(map[RegionCoord]*Region)
+	for coord, indices := range cp.Regions {
+		region := NewRegion(coord)
+		for _, i := range indices {
+			region.AddNode(s.registeredNodes[i])
+		}
+		s.regionMap.regionMap[coord] = region
+	}
+
+	for i, nodeCp := range cp.Nodes {
+		iNode := s.registeredNodes[i]
+		for k, j := range nodeCp.Neighbours {
+			target := s.registeredNodes[j]
+			iNode.peers[target.internalID] = Peer{target: target, origin: iNode}
+			iNode.connectedAt[target.internalID] = nodeCp.ConnectedAt[k]
+		}
+	}
+
+	for _, peerCp := range cp.Peers {
+		peers = append(peers, InternalPeer{
+			a:     s.registeredNodes[peerCp.A],
+			b:     s.registeredNodes[peerCp.B],
+			dataA: s.decodeData(peerCp.DataA),
+			dataB: s.decodeData(peerCp.DataB),
+		})
+	}
+	s.peers.restore(peers)
+
+	s.discoveries = make(map[nodePair]*discovery)
+	for _, discoveryCp := range cp.Discoveries {
+		nodeA := s.registeredNodes[discoveryCp.A]
+		nodeB := s.registeredNodes[discoveryCp.B]
+		s.discoveries[newNodePair(nodeA, nodeB)] = &discovery{nodeA, nodeB, discoveryCp.Start, discoveryCp.Time, discoveryCp.Fail, discoveryCp.Lifetime, nil}
+	}
+
+	// Drops are linked to their disconnect events when the event queue is restored
+	s.drops = make(map[nodePair]*DisconnectEvent)
+	dropTimes := make(map[nodePair]time.Duration)
+	for _, dropCp := range cp.Drops {
+		dropTimes[newNodePair(s.registeredNodes[dropCp.A], s.registeredNodes[dropCp.B])] = dropCp.Time
+	}
+
+	partition := func(index int) (*scheduledPartition, error) {
+		if index >= len(s.partitions) {
+			return nil, fmt.Errorf("checkpoint refers to partition %d, but %d partitions have been scheduled", index, len(s.partitions))
+		}
+		return s.partitions[index], nil
+	}
+	s.regionMap.partitions = nil
+	for _, index := range cp.Partitions {
+		p, err := partition(index)
+		if err != nil {
+			return err
+		}
+		s.regionMap.partitions = append(s.regionMap.partitions, p)
+	}
+
+	s.declined = make(map[nodePair][2]*InternalNode)
+	for _, indices := range cp.Declined {
+		nodeA := s.registeredNodes[indices[0]]
+		nodeB := s.registeredNodes[indices[1]]
+		s.declined[newNodePair(nodeA, nodeB)] = [2]*InternalNode{nodeA, nodeB}
+	}
+
+	s.lastEvent = &TimestepEvent{
+		BaseEvent: BaseEvent{time: s.time, sequenceNumber: -1},
+	}
+
+	for _, eventCp := range cp.Events {
+		base := BaseEvent{
+			time:           eventCp.Time,
+			sequenceNumber: eventCp.SequenceNumber,
+			parentEvent:    s.lastEvent,
+			duplicate:      eventCp.Duplicate,
+			intercepted:    eventCp.Intercepted,
+		}
+		var nodeA, nodeB *InternalNode
+		if len(s.registeredNodes) > 0 {
+			nodeA = s.registeredNodes[eventCp.NodeA]
+			nodeB = s.registeredNodes[eventCp.NodeB]
+		}
+
+		var e Event
+		switch eventCp.EventType {
+		case TIMESTEP:
+			e = &TimestepEvent{BaseEvent: base}
+		case MOVEMENT:
+			e = &MovementEvent{BaseEvent: base, node: nodeA}
+		case CONNECT:
+			e = &ConnectEvent{BaseEvent: base, nodeA: nodeA, nodeB: nodeB}
+			if d, found := s.discoveries[newNodePair(nodeA, nodeB)]; found && d.time == e.Time() {
+				d.event = e.(*ConnectEvent)
+			}
+			if s.options.ContactPrediction {
+				pair := newNodePair(nodeA, nodeB)
+				s.predictions[pair] = append(s.predictions[pair], e)
+			}
+		case DISCONNECT:
+			e = &DisconnectEvent{BaseEvent: base, nodeA: nodeA, nodeB: nodeB}
+			pair := newNodePair(nodeA, nodeB)
+			if dropTime, found := dropTimes[pair]; found && dropTime == e.Time() && s.drops[pair] == nil {
+				s.drops[pair] = e.(*DisconnectEvent)
+			} else if s.options.ContactPrediction {
+				s.predictions[pair] = append(s.predictions[pair], e)
+			} else {
+				s.departures[pair] = e.(*DisconnectEvent)
+			}
+		case ADD_NODE:
+			e = &AddNodeEvent{BaseEvent: base, node: nodeA}
+		case RM_NODE:
+			e = &RemoveNodeEvent{BaseEvent: base, node: nodeA}
+		case SEND_MSG:
+			e = &SendEvent{
+				BaseEvent:       base,
+				target:          nodeB,
+				origin:          nodeA,
+				targetNodeID:    eventCp.NodeID,
+				peer:            Peer{target: nodeB, origin: nodeA},
+				shouldBeDropped: eventCp.ShouldBeDropped,
+				delay:           eventCp.Delay,
+				packet:          eventCp.Packet,
+			}
+			if !eventCp.Duplicate {
+				nodeA.queue = append(nodeA.queue, e.(*SendEvent))
+			}
+			if eventCp.Queued {
+				continue
+			}
+		case RCV_MSG:
+			e = &ReceiveEvent{
+				BaseEvent:    base,
+				target:       nodeB,
+				origin:       nodeA,
+				originNodeID: eventCp.NodeID,
+				peer:         Peer{target: nodeA, origin: nodeB},
+				packet:       eventCp.Packet,
+			}
+		case PARTITION, HEAL:
+			p, err := partition(eventCp.Partition)
+			if err != nil {
+				return err
+			}
+			if eventCp.EventType == PARTITION {
+				e = &PartitionEvent{BaseEvent: base, partition: p}
+			} else {
+				e = &HealEvent{BaseEvent: base, partition: p}
+			}
+		case CRASH:
+			e = &CrashEvent{BaseEvent: base, node: nodeA, downtime: eventCp.Delay, random: eventCp.Random}
+			if eventCp.Random {
+				nodeA.nextCrash = e.(*CrashEvent)
+			}
+		case RESTART:
+			e = &RestartEvent{BaseEvent: base, node: nodeA}
+		case DEPLETE:
+			e = &DepleteEvent{BaseEvent: base, node: nodeA}
+			if nodeA.energy == nil {
+				return fmt.Errorf("checkpoint depletes node %d, which has no energy profile", nodeA.nodeID)
+			}
+			nodeA.energy.depletion = e.(*DepleteEvent)
+		case TERMINATE:
+			var err error
+			if eventCp.Err != "" {
+				err = errors.New(eventCp.Err)
+			}
+			e = &TerminateEvent{BaseEvent: base, err: err}
+		default:
+			return fmt.Errorf("cannot restore event of type %s", eventCp.EventType)
+		}
+
+		heap.Push(s.eventQueue, e)
+	}
+
+	s.isRunning = true
+	for _, logger := range s.loggers {
+		logger.Init()
+	}
+
+	for i, nodeCp := range cp.Nodes {
+		iNode := s.registeredNodes[i]
+		// Crashed nodes are started by their pending restart event
+		if !nodeCp.Added || nodeCp.Removed || nodeCp.Crashed {
+			continue
+		}
+
+		if nodeCp.Node != nil {
+			snapshotter, ok := iNode.node.(NodeSnapshotter)
+			if !ok {
+				return fmt.Errorf("node %d does not implement NodeSnapshotter", iNode.nodeID)
+			}
+			connections := []Connection{}
+			for _, j := range nodeCp.Neighbours {
+				target := s.registeredNodes[j]
+				connections = append(connections, Connection{Peer: iNode.peers[target.internalID], ID: target.nodeID})
+			}
+			if err := snapshotter.OnRestore(iNode.nodeArguments(), nodeCp.Node, connections); err != nil {
+				return err
+			}
+		} else {
+			iNode.startNode()
+		}
+	}
+
+	// Nodes without a snapshot have been started from scratch, so they are informed about their existing connections
+	for i, nodeCp := range cp.Nodes {
+		iNode := s.registeredNodes[i]
+		if nodeCp.Node != nil {
+			continue
+		}
+		for _, j := range nodeCp.Neighbours {
+			target := s.registeredNodes[j]
+			iNode.node.OnConnect(iNode.peers[target.internalID], target.nodeID)
+		}
+	}
+
+	return nil
+}
+
+// SaveCheckpoint writes a checkpoint of the simulator to the file at the given path.
+func (s *Simulator) SaveCheckpoint(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.Checkpoint(f)
+}
+
+// LoadCheckpoint restores the simulator from a checkpoint file written by SaveCheckpoint.
+func (s *Simulator) LoadCheckpoint(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.Restore(f)
+}
+
+// encodeData encodes each value of a data map separately.
+// Values that cannot be encoded, because their type has not been registered using gob.Register, are skipped.
+func (s *Simulator) encodeData(data map[string]interface{}) map[string][]byte {
+	encoded := make(map[string][]byte)
+	for key, value := range data {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+			s.logDebug(fmt.Sprintf("simulator:checkpoint:skip_data:%s", key))
+			continue
+		}
+		encoded[key] = buf.Bytes()
+	}
+	return encoded
+}
+
+func (s *Simulator) decodeData(encoded map[string][]byte) map[string]interface{} {
+	data := make(map[string]interface{})
+	for key, value := range encoded {
+		var decoded interface{}
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&decoded); err != nil {
+			s.logDebug(fmt.Sprintf("simulator:checkpoint:skip_data:%s", key))
+			continue
+		}
+		data[key] = decoded
+	}
+	return data
+}
@@ -0,0 +1,37 @@
+package simulator
+
+import "time"
+
+// A Clock describes the local clock of a node, which is Offset ahead of the simulated time when the simulation starts
+// and runs Drift faster than it, e.g. a clock with a drift of 50e-6 gains 50 µs every second.
+// The local time is returned by NodeArguments.Now, and the delays given to NodeArguments.DelayBy are measured by the local clock.
+// The zero value is a clock that is synchronized with the simulated time.
+type Clock struct {
+	Offset time.Duration
+	Drift  float64
+}
+
+// LocalTime returns the time shown by the clock at the given simulated time
+func (c Clock) LocalTime(t time.Duration) time.Duration {
+	if c.Drift == 0 {
+		return t + c.Offset
+	}
+	return t + c.Offset + time.Duration(float64(t)*c.Drift)
+}
+
+// simulatedDuration returns the simulated time that passes while the clock measures the given duration
+func (c Clock) simulatedDuration(d time.Duration) time.Duration {
+	if c.Drift == 0 {
+		return d
+	}
+	return time.Duration(float64(d) / (1 + c.Drift))
+}
+
+func (n *InternalNode) Clock() Clock {
+	return n.clock
+}
+
+// now returns the local time of the node
+func (n *InternalNode) now() time.Time {
+	return time.Unix(0, 0).Add(n.clock.LocalTime(n.sim.time))
+}
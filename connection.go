@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+)
+
+// tracksConnectionAttempts reports whether connections are delayed or can fail,
+// in which case each pair of nodes within range that are not connected yet is tracked by a discovery
+func (s *Simulator) tracksConnectionAttempts() bool {
+	return s.options.Discovery != nil || s.options.Connection != nil
+}
+
+// failConnection is called before the nodes are connected by the connect event, and reports whether the connection attempt fails
+func (s *Simulator) failConnection(connectEvent *ConnectEvent) bool {
+	d, found := s.discoveries[newNodePair(connectEvent.nodeA, connectEvent.nodeB)]
+	if !found || d.event != connectEvent || !d.fail {
+		return false
+	}
+
+	s.logDebug(fmt.Sprintf("simulator:connection:failed:%d:%d", connectEvent.nodeA.nodeID, connectEvent.nodeB.nodeID))
+	s.completeDiscovery(connectEvent.nodeA, connectEvent.nodeB)
+	s.reconnect(connectEvent.nodeA, connectEvent.nodeB)
+	return true
+}
+
+// reconnect lets the nodes attempt to connect again, after a failed attempt or a dropped connection.
+// When ContactPrediction is disabled, this happens at the next time step if they are still within range.
+func (s *Simulator) reconnect(nodeA *InternalNode, nodeB *InternalNode) {
+	if s.options.ContactPrediction {
+		s.predictContactFrom(nodeA, nodeB, s.time+s.options.TimeStep)
+	} else {
+		nodeA.dirty = true
+		nodeB.dirty = true
+	}
+}
+
+// completeDrop reports whether the disconnect event is the spontaneous drop of the connection,
+// otherwise the drop is cancelled, since the nodes are disconnected for another reason
+func (s *Simulator) completeDrop(disconnectEvent *DisconnectEvent) bool {
+	pair := newNodePair(disconnectEvent.nodeA, disconnectEvent.nodeB)
+	drop, found := s.drops[pair]
+	if !found {
+		return false
+	}
+	if drop != disconnectEvent {
+		s.cancelDrop(disconnectEvent.nodeA, disconnectEvent.nodeB)
+		return false
+	}
+	delete(s.drops, pair)
+	s.logDebug(fmt.Sprintf("simulator:connection:dropped:%d:%d", disconnectEvent.nodeA.nodeID, disconnectEvent.nodeB.nodeID))
+	return true
+}
+
+// cancelDrop cancels the spontaneous drop of the connection between the nodes, if there is one
+func (s *Simulator) cancelDrop(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	if drop, found := s.drops[pair]; found {
+		drop.cancelled = true
+		delete(s.drops, pair)
+	}
+}
+
+func (s *Simulator) forgetDrops(node *InternalNode) {
+	for pair, drop := range s.drops {
+		if drop.nodeA == node || drop.nodeB == node {
+			delete(s.drops, pair)
+		}
+	}
+	for pair, departure := range s.departures {
+		if departure.nodeA == node || departure.nodeB == node {
+			delete(s.departures, pair)
+		}
+	}
+}
+
+// completeDeparture forgets the disconnect event of the nodes leaving range, once it is dispatched
+func (s *Simulator) completeDeparture(disconnectEvent *DisconnectEvent) {
+	pair := newNodePair(disconnectEvent.nodeA, disconnectEvent.nodeB)
+	if s.departures[pair] == disconnectEvent {
+		delete(s.departures, pair)
+	}
+}
+
+// cancelDeparture cancels the disconnect event of the nodes leaving range, if there is one
+func (s *Simulator) cancelDeparture(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	if departure, found := s.departures[pair]; found {
+		departure.cancelled = true
+		delete(s.departures, pair)
+	}
+}
+
+// connectionAttempt returns the outcome of an attempt to connect the nodes, according to the connection behavior of the simulator
+func (s *Simulator) connectionAttempt(nodeA *InternalNode, nodeB *InternalNode) (shouldFail bool, handshake time.Duration, lifetime time.Duration) {
+	if s.options.Connection == nil {
+		return false, 0, 0
+	}
+	return s.options.Connection.Connection(nodeA.coords, nodeB.coords)
+}
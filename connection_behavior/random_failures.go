@@ -0,0 +1,25 @@
+package connection_behavior
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+type RandomFailures struct {
+	FailureChance float64       // The chance that a connection attempt fails
+	Handshake     time.Duration // The time it takes to establish a connection, attempts fail after the same time
+	MeanLifetime  time.Duration // The mean of the exponentially distributed time until a connection drops, connections do not drop if it is 0
+	Random        *rand.Rand
+}
+
+func (c RandomFailures) Connection(coordA simulator.Coordinate, coordB simulator.Coordinate) (shouldFail bool, handshake time.Duration, lifetime time.Duration) {
+	if c.Random.Float64() < c.FailureChance {
+		return true, c.Handshake, 0
+	}
+	if c.MeanLifetime > 0 {
+		lifetime = time.Duration(c.Random.ExpFloat64() * float64(c.MeanLifetime))
+	}
+	return false, c.Handshake, lifetime
+}
@@ -0,0 +1,16 @@
+package connection_behavior
+
+import (
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// Reliable connections always succeed after the handshake, and last until the nodes move apart
+type Reliable struct {
+	Handshake time.Duration
+}
+
+func (c Reliable) Connection(coordA simulator.Coordinate, coordB simulator.Coordinate) (shouldFail bool, handshake time.Duration, lifetime time.Duration) {
+	return false, c.Handshake, 0
+}
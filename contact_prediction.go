@@ -0,0 +1,361 @@
+package simulator
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// A nodePair identifies an unordered pair of nodes
+type nodePair struct {
+	a InternalID
+	b InternalID
+}
+
+func newNodePair(nodeA *InternalNode, nodeB *InternalNode) nodePair {
+	if nodeA.internalID < nodeB.internalID {
+		return nodePair{nodeA.internalID, nodeB.internalID}
+	}
+	return nodePair{nodeB.internalID, nodeA.internalID}
+}
+
+// nextMovement is used when ContactPrediction is enabled.
+// It starts the next movement instruction of the node and predicts its contacts with all other nodes.
+func (s *Simulator) nextMovement(node *InternalNode) {
+	s.refreshPosition(node)
+
+	node.movementInstruction = node.nodeMovement.RegisterMovements(node.coords)
+	node.segmentStart = node.coords
+	node.segmentStartTime = s.time
+
+	if node.movementInstruction.Time > 0 {
+		node.segmentEndTime = s.time + node.movementInstruction.Time
+	} else {
+		// Like in updateLocations, instructions without a duration are skipped without moving the node
+		node.movementInstruction.Coords = node.coords
+		node.segmentEndTime = s.time + s.options.TimeStep
+	}
+	s.pushMovementEvent(node.segmentEndTime, node)
+
+	for _, other := range s.nodes {
+		if other != node {
+			s.predictContact(node, other)
+		}
+	}
+}
+
+// predictContact cancels any previous predictions for the two nodes,
+// and schedules connect and disconnect events for when they enter and leave range,
+// until one of them changes its movement.
+func (s *Simulator) predictContact(nodeA *InternalNode, nodeB *InternalNode) {
+	s.predictContactFrom(nodeA, nodeB, s.time)
+}
+
+// predictContactFrom predicts the contact like predictContact, but the nodes do not attempt to connect before earliest
+func (s *Simulator) predictContactFrom(nodeA *InternalNode, nodeB *InternalNode, earliest time.Duration) {
+	pair := newNodePair(nodeA, nodeB)
+	for _, e := range s.predictions[pair] {
+		e.base().cancelled = true
+	}
+	delete(s.predictions, pair)
+
+	s.refreshPosition(nodeA)
+	s.refreshPosition(nodeB)
+
+	if nodeA.crashed || nodeB.crashed || s.regionMap.separated(nodeA, nodeB) {
+		// The nodes stay apart until the partition heals or the node restarts, when their contact is predicted again
+		if nodeA.hasNeighbour(nodeB) {
+			s.predictions[pair] = []Event{s.pushDisconnectEvent(s.time, nodeA, nodeB)}
+		} else {
+			s.abortDiscovery(nodeA, nodeB)
+		}
+		return
+	}
+
+	horizon := min(nodeA.segmentEndTime, nodeB.segmentEndTime) - s.time
+
+	velocityA := nodeA.velocity()
+	velocityB := nodeB.velocity()
+	position := Coordinate{X: nodeB.coords.X - nodeA.coords.X, Y: nodeB.coords.Y - nodeA.coords.Y, Z: nodeB.coords.Z - nodeA.coords.Z}
+	velocity := Coordinate{X: velocityB.X - velocityA.X, Y: velocityB.Y - velocityA.Y, Z: velocityB.Z - velocityA.Z}
+
+	// The nodes only change floor at the end of their movement segments, so the floor loss is constant until the horizon
+	rangeSquared := contactRangeSquared(s.nodeRange, nodeA, nodeB) * floorFactorSquared(s.options.FloorAttenuation, nodeA.coords, nodeB.coords)
+	inRange := nodeDistSquared(nodeA, nodeB) < rangeSquared
+	enter, exit, crosses := rangeCrossings(position, velocity, rangeSquared)
+	if s.options.Topography != nil {
+		// Crossings that do not happen before the horizon are -1, so the checks below ignore them
+		inRange, enter, exit = obstructedRangeCrossings(s.options.Topography, nodeA, nodeB, rangeSquared, horizon)
+		crosses = true
+	}
+
+	predictions := []Event{}
+	predictExit := func() {
+		if crosses && exit >= 0 && exit <= horizon {
+			predictions = append(predictions, s.pushDisconnectEvent(s.time+exit, nodeA, nodeB))
+		}
+	}
+
+	if nodeA.hasNeighbour(nodeB) {
+		if !inRange {
+			predictions = append(predictions, s.pushDisconnectEvent(s.time, nodeA, nodeB))
+		} else {
+			predictExit()
+		}
+	} else if inRange && s.isDeclined(nodeA, nodeB) {
+		// The nodes have declined to connect, and stay apart until they are predicted out of range
+		return
+	} else {
+		if !inRange {
+			s.abortDiscovery(nodeA, nodeB)
+			delete(s.declined, pair)
+		}
+
+		var connectTime time.Duration
+		if inRange {
+			connectTime = s.startDiscovery(nodeA, nodeB, max(s.time, earliest))
+		} else if crosses && enter >= 0 && enter <= horizon {
+			connectTime = s.startDiscovery(nodeA, nodeB, max(s.time+enter, earliest))
+		} else {
+			connectTime = -1
+		}
+
+		// The nodes only connect if they are discovered before they leave range again,
+		// discoveries that end after the horizon are predicted again when the nodes change their movement
+		exitsFirst := s.tracksConnectionAttempts() && crosses && exit >= 0 && exit <= horizon && connectTime >= s.time+exit
+		if connectTime >= 0 && connectTime <= s.time+horizon && !exitsFirst {
+			predictions = append(predictions, s.pushDiscoveryConnectEvent(connectTime, nodeA, nodeB))
+			predictExit()
+		}
+	}
+
+	if len(predictions) > 0 {
+		s.predictions[pair] = predictions
+	}
+}
+
+// rangeCrossings solves |position + velocity*t|^2 = rangeSquared for t,
+// where position and velocity are relative between two nodes and velocity is given in units per second.
+// The crossing times are rounded up, such that the nodes are within range at enter and out of range at exit.
+func rangeCrossings(position Coordinate, velocity Coordinate, rangeSquared float64) (enter time.Duration, exit time.Duration, crosses bool) {
+	a := velocity.X*velocity.X + velocity.Y*velocity.Y + velocity.Z*velocity.Z
+	b := 2 * (position.X*velocity.X + position.Y*velocity.Y + position.Z*velocity.Z)
+	c := position.X*position.X + position.Y*position.Y + position.Z*position.Z - rangeSquared
+
+	if a == 0 {
+		return 0, 0, false
+	}
+
+	discriminant := b*b - 4*a*c
+	if discriminant <= 0 {
+		return 0, 0, false
+	}
+
+	sqrtDiscriminant := math.Sqrt(discriminant)
+	t1 := (-b - sqrtDiscriminant) / (2 * a)
+	t2 := (-b + sqrtDiscriminant) / (2 * a)
+
+	enter = time.Duration(math.Ceil(t1 * float64(time.Second)))
+	exit = time.Duration(math.Ceil(t2 * float64(time.Second)))
+	return enter, exit, true
+}
+
+// lineOfSightTolerance is the shortest time the nodes must be within range with obstacles between them.
+// The nodes are predicted again when they leave range, and crossings recomputed right after they happened must not bring them back.
+const lineOfSightTolerance = time.Microsecond
+
+// obstructedRangeCrossings is used instead of rangeCrossings when there are obstacles between the nodes.
+// The line of sight between the nodes only changes the obstacles it crosses at the times returned by lineOfSightChanges,
+// so the crossings are solved for each segment between those times with the attenuation of the obstacles it crosses.
+// It reports whether the nodes are within range now, when they enter range, and when they leave range after entering it.
+// The times are -1 if the nodes do not enter or leave range before the horizon.
+func obstructedRangeCrossings(topography *Topography, nodeA *InternalNode, nodeB *InternalNode, rangeSquared float64, horizon time.Duration) (inRange bool, enter time.Duration, exit time.Duration) {
+	if horizon <= 0 {
+		if nodeDistSquared(nodeA, nodeB) < rangeSquared*obstacleFactorSquared(topography, nodeA.coords, nodeB.coords) {
+			return true, 0, -1
+		}
+		return false, -1, -1
+	}
+
+	velocityA := nodeA.velocity()
+	velocityB := nodeB.velocity()
+	position := Coordinate{X: nodeB.coords.X - nodeA.coords.X, Y: nodeB.coords.Y - nodeA.coords.Y, Z: nodeB.coords.Z - nodeA.coords.Z}
+	velocity := Coordinate{X: velocityB.X - velocityA.X, Y: velocityB.Y - velocityA.Y, Z: velocityB.Z - velocityA.Z}
+	positionAt := func(coords Coordinate, velocity Coordinate, t time.Duration) Coordinate {
+		return Coordinate{X: coords.X + velocity.X*t.Seconds(), Y: coords.Y + velocity.Y*t.Seconds(), Z: coords.Z + velocity.Z*t.Seconds()}
+	}
+
+	enter, exit = -1, -1
+	start := time.Duration(0)
+	for _, end := range append(lineOfSightChanges(topography, nodeA.coords, velocityA, nodeB.coords, velocityB, horizon), horizon) {
+		// The obstacles crossed by the line of sight are the same throughout the segment
+		middle := start + (end-start)/2
+		segmentRangeSquared := rangeSquared * obstacleFactorSquared(topography, positionAt(nodeA.coords, velocityA, middle), positionAt(nodeB.coords, velocityB, middle))
+
+		// The nodes are within range from low until high during the segment
+		low, high := start, end
+		if segmentEnter, segmentExit, crosses := rangeCrossings(position, velocity, segmentRangeSquared); crosses {
+			low, high = max(low, segmentEnter), min(high, segmentExit)
+		} else if position.X*position.X+position.Y*position.Y+position.Z*position.Z >= segmentRangeSquared || velocity != (Coordinate{}) {
+			low, high = end, start
+		}
+
+		if enter < 0 {
+			if high-low > lineOfSightTolerance {
+				enter = low
+				if high < end {
+					exit = high
+					break
+				}
+			}
+		} else if low > start || high < low {
+			exit = start
+			break
+		} else if high < end {
+			exit = high
+			break
+		}
+		start = end
+	}
+	return enter == 0, enter, exit
+}
+
+// lineOfSightChanges returns the times before the horizon at which the line of sight between two nodes moving with constant velocities
+// may change the obstacles it crosses. That is when one of the nodes crosses the line through an edge of an obstacle,
+// or the line of sight passes a corner of an obstacle. The times are sorted.
+func lineOfSightChanges(topography *Topography, a Coordinate, velocityA Coordinate, b Coordinate, velocityB Coordinate, horizon time.Duration) []time.Duration {
+	seconds := horizon.Seconds()
+	endA := Coordinate{X: a.X + velocityA.X*seconds, Y: a.Y + velocityA.Y*seconds}
+	endB := Coordinate{X: b.X + velocityB.X*seconds, Y: b.Y + velocityB.Y*seconds}
+	lowerX, upperX := min(a.X, endA.X, b.X, endB.X), max(a.X, endA.X, b.X, endB.X)
+	lowerY, upperY := min(a.Y, endA.Y, b.Y, endB.Y), max(a.Y, endA.Y, b.Y, endB.Y)
+
+	changes := []time.Duration{}
+	addRoot := func(t float64) {
+		// The changes are rounded up, such that the line of sight has changed at the returned times
+		if change := time.Duration(math.Ceil(t * float64(time.Second))); change > lineOfSightTolerance && change < horizon {
+			changes = append(changes, change)
+		}
+	}
+	addRoots := func(a float64, b float64, c float64) {
+		if a == 0 {
+			if b != 0 {
+				addRoot(-c / b)
+			}
+			return
+		}
+		discriminant := b*b - 4*a*c
+		if discriminant < 0 {
+			return
+		}
+		sqrtDiscriminant := math.Sqrt(discriminant)
+		addRoot((-b - sqrtDiscriminant) / (2 * a))
+		addRoot((-b + sqrtDiscriminant) / (2 * a))
+	}
+
+	direction := Coordinate{X: b.X - a.X, Y: b.Y - a.Y}
+	directionVelocity := Coordinate{X: velocityB.X - velocityA.X, Y: velocityB.Y - velocityA.Y}
+	for i := range topography.Obstacles {
+		points := topography.Obstacles[i].Points
+		if len(points) == 0 {
+			continue
+		}
+
+		// The line of sight stays within the bounding box of the movements, so obstacles outside of it are never crossed
+		lower, upper := points[0], points[0]
+		for _, p := range points[1:] {
+			lower = Coordinate{X: min(lower.X, p.X), Y: min(lower.Y, p.Y)}
+			upper = Coordinate{X: max(upper.X, p.X), Y: max(upper.Y, p.Y)}
+		}
+		if upperX < lower.X || lowerX > upper.X || upperY < lower.Y || lowerY > upper.Y {
+			continue
+		}
+
+		for j, p := range points {
+			q := points[(j+1)%len(points)]
+			edge := Coordinate{X: q.X - p.X, Y: q.Y - p.Y}
+			// The nodes cross the line through the edge
+			addRoots(0, edge.X*velocityA.Y-edge.Y*velocityA.X, orientation(p, q, a))
+			addRoots(0, edge.X*velocityB.Y-edge.Y*velocityB.X, orientation(p, q, b))
+
+			// The line of sight passes the corner
+			offset := Coordinate{X: p.X - a.X, Y: p.Y - a.Y}
+			addRoots(
+				velocityA.X*directionVelocity.Y-velocityA.Y*directionVelocity.X,
+				directionVelocity.X*offset.Y-direction.X*velocityA.Y-directionVelocity.Y*offset.X+direction.Y*velocityA.X,
+				direction.X*offset.Y-direction.Y*offset.X,
+			)
+		}
+	}
+
+	slices.Sort(changes)
+	return slices.Compact(changes)
+}
+
+func (s *Simulator) forgetPredictions(node *InternalNode) {
+	for pair, predictions := range s.predictions {
+		if pair.a == node.internalID || pair.b == node.internalID {
+			for _, e := range predictions {
+				e.base().cancelled = true
+			}
+			delete(s.predictions, pair)
+		}
+	}
+}
+
+func (s *Simulator) refreshPosition(node *InternalNode) {
+	s.moveNodeTo(node, node.PositionAt(s.time))
+}
+
+func (s *Simulator) refreshPositions(t time.Duration) {
+	for _, node := range s.nodes {
+		s.moveNodeTo(node, node.PositionAt(t))
+	}
+}
+
+func (s *Simulator) moveNodeTo(node *InternalNode, coords Coordinate) {
+	if coords != node.coords {
+		s.markMoved(node)
+	}
+	s.regionMap.MoveNode(node, node.coords, coords)
+	node.coords = coords
+}
+
+// PositionAt returns the position of the node at the given time, according to its current movement segment.
+// Without ContactPrediction the node has no movement segments, and its current position is returned.
+func (n *InternalNode) PositionAt(t time.Duration) Coordinate {
+	duration := n.segmentEndTime - n.segmentStartTime
+	if duration <= 0 {
+		return n.coords
+	}
+
+	progress := float64(t-n.segmentStartTime) / float64(duration)
+	progress = max(0, min(1, progress))
+
+	target := n.movementInstruction.Coords
+	floor := n.segmentStart.Floor
+	if progress >= 1 {
+		floor = target.Floor
+	}
+	return Coordinate{
+		X:     n.segmentStart.X + (target.X-n.segmentStart.X)*progress,
+		Y:     n.segmentStart.Y + (target.Y-n.segmentStart.Y)*progress,
+		Z:     n.segmentStart.Z + (target.Z-n.segmentStart.Z)*progress,
+		Floor: floor,
+	}
+}
+
+// velocity returns the velocity of the node in units per second during its current movement segment
+func (n *InternalNode) velocity() Coordinate {
+	duration := (n.segmentEndTime - n.segmentStartTime).Seconds()
+	if duration <= 0 {
+		return Coordinate{X: 0, Y: 0}
+	}
+
+	target := n.movementInstruction.Coords
+	return Coordinate{
+		X: (target.X - n.segmentStart.X) / duration,
+		Y: (target.Y - n.segmentStart.Y) / duration,
+		Z: (target.Z - n.segmentStart.Z) / duration,
+	}
+}
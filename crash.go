@@ -0,0 +1,139 @@
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/starling-protocol/starling/utils"
+)
+
+// A CrashModel decides when a node crashes by itself. When the node starts or restarts, Crash returns the time
+// until it crashes and how long it stays down before it restarts. The node does not crash again if uptime is negative.
+type CrashModel interface {
+	Crash() (uptime time.Duration, downtime time.Duration)
+}
+
+// ScheduleCrash crashes the node with the given ID at the given time, and restarts it after downtime.
+// A crashed node loses its connections, its queued packets and the functions it has delayed with DelayBy, and it cannot
+// connect to other nodes until it restarts, while it keeps moving. When the node restarts, OnStart is called again.
+func (s *Simulator) ScheduleCrash(id NodeID, at time.Duration, downtime time.Duration) {
+	if at < s.time {
+		panic("crash cannot be scheduled in the past")
+	}
+	if downtime <= 0 {
+		panic("crash downtime must be positive")
+	}
+	for _, iNode := range s.registeredNodes {
+		if iNode.nodeID == id {
+			s.pushCrashEvent(at, iNode, downtime, false)
+			return
+		}
+	}
+	panic(fmt.Sprintf("cannot crash unknown node %d", id))
+}
+
+func (n *InternalNode) Crashed() bool {
+	return n.crashed
+}
+
+// scheduleRandomCrash schedules the next crash of the node according to its crash model, if it has one
+func (s *Simulator) scheduleRandomCrash(iNode *InternalNode) {
+	if iNode.crashModel == nil {
+		return
+	}
+	uptime, downtime := iNode.crashModel.Crash()
+	if uptime < 0 {
+		return
+	}
+	iNode.nextCrash = s.pushCrashEvent(s.time+uptime, iNode, max(downtime, 1), true)
+}
+
+func (s *Simulator) crashNode(iNode *InternalNode, downtime time.Duration) {
+	if iNode.removed || iNode.crashed || !slices.Contains(s.nodes, iNode) {
+		return
+	}
+	s.logDebug(fmt.Sprintf("simulator:node:crash:%d", iNode.nodeID))
+	iNode.crashed = true
+	if iNode.nextCrash != nil {
+		iNode.nextCrash.cancelled = true
+		iNode.nextCrash = nil
+	}
+
+	s.dropNodeEvents(iNode)
+	s.forgetPredictions(iNode)
+	s.forgetDiscoveries(iNode)
+	s.forgetDeclined(iNode)
+	s.forgetDrops(iNode)
+
+	// The peers observe the disconnections, while the crashed node does not
+	for _, internalID := range utils.ShuffleMapKeys(iNode.random, iNode.peers) {
+		peer := iNode.peers[internalID].target
+		disconnectEvent := s.NewDisconnectEvent(s.time, iNode, peer)
+		s.currentSequenceNumber++
+		s.updateLoggers(disconnectEvent)
+
+		delete(iNode.peers, internalID)
+		delete(iNode.connectedAt, internalID)
+		peer.disconnectNodes(iNode)
+		s.reconsiderDeclined(peer)
+	}
+	s.peers.removeNode(iNode)
+	s.updateEnergy(iNode)
+
+	s.pushRestartEvent(s.time+downtime, iNode)
+}
+
+func (s *Simulator) restartNode(iNode *InternalNode) {
+	if iNode.removed || !iNode.crashed {
+		return
+	}
+	s.logDebug(fmt.Sprintf("simulator:node:restart:%d", iNode.nodeID))
+	iNode.crashed = false
+	iNode.restarts++
+	s.updateEnergy(iNode)
+
+	iNode.startNode()
+	iNode.nodeID = iNode.node.ID()
+	s.scheduleRandomCrash(iNode)
+
+	if s.options.ContactPrediction {
+		for _, other := range s.nodes {
+			if other != iNode {
+				s.predictContact(iNode, other)
+			}
+		}
+	} else {
+		iNode.dirty = true
+	}
+}
+
+func (s *Simulator) pushCrashEvent(time time.Duration, node *InternalNode, downtime time.Duration, random bool) *CrashEvent {
+	event := &CrashEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		node:     node,
+		downtime: downtime,
+		random:   random,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+	return event
+}
+
+func (s *Simulator) pushRestartEvent(time time.Duration, node *InternalNode) {
+	event := &RestartEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		node: node,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+}
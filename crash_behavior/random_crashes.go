@@ -0,0 +1,18 @@
+package crash_behavior
+
+import (
+	"math/rand"
+	"time"
+)
+
+type RandomCrashes struct {
+	MeanUptime   time.Duration // The mean of the exponentially distributed time until the node crashes
+	MeanDowntime time.Duration // The mean of the exponentially distributed time until the node restarts
+	Random       *rand.Rand
+}
+
+func (c RandomCrashes) Crash() (uptime time.Duration, downtime time.Duration) {
+	uptime = time.Duration(c.Random.ExpFloat64() * float64(c.MeanUptime))
+	downtime = time.Duration(c.Random.ExpFloat64() * float64(c.MeanDowntime))
+	return uptime, downtime
+}
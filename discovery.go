@@ -0,0 +1,163 @@
+package simulator
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// A DiscoveryModel decides how long it takes two nodes to discover each other and establish a connection,
+// after they have come within range. Nodes that leave range again before that never connect.
+type DiscoveryModel interface {
+	// DiscoveryDelay returns the time from the nodes coming within range until they are connected
+	DiscoveryDelay(coordA Coordinate, coordB Coordinate, random *rand.Rand) time.Duration
+}
+
+// FixedDiscovery connects nodes after a fixed delay
+type FixedDiscovery struct {
+	Delay time.Duration
+}
+
+func (d FixedDiscovery) DiscoveryDelay(coordA Coordinate, coordB Coordinate, random *rand.Rand) time.Duration {
+	return d.Delay
+}
+
+// BleDiscovery models BLE advertising and scanning, where both nodes advertise and scan.
+// A node is discovered by the first of its advertisements that falls within a scan window of the other node,
+// after which the connection takes ConnectionSetup to establish.
+type BleDiscovery struct {
+	AdvertisingInterval time.Duration
+	ScanInterval        time.Duration
+	ScanWindow          time.Duration
+	ConnectionSetup     time.Duration
+}
+
+// DefaultBleDiscovery returns the parameters of the balanced advertising and scan modes of Android
+func DefaultBleDiscovery() BleDiscovery {
+	return BleDiscovery{
+		AdvertisingInterval: 250 * time.Millisecond,
+		ScanInterval:        4096 * time.Millisecond,
+		ScanWindow:          1024 * time.Millisecond,
+		ConnectionSetup:     50 * time.Millisecond,
+	}
+}
+
+// maxAdvertisingDelay is the random delay added to every advertising interval by the BLE specification
+const maxAdvertisingDelay = 10 * time.Millisecond
+
+func (d BleDiscovery) DiscoveryDelay(coordA Coordinate, coordB Coordinate, random *rand.Rand) time.Duration {
+	if d.AdvertisingInterval <= 0 || d.ScanInterval <= 0 || d.ScanWindow <= 0 {
+		panic("BLE discovery needs positive advertising interval, scan interval and scan window")
+	}
+	return min(d.scan(random), d.scan(random)) + d.ConnectionSetup
+}
+
+// scan returns the time until an advertiser with a random phase is heard by a scanner with a random phase
+func (d BleDiscovery) scan(random *rand.Rand) time.Duration {
+	if d.ScanWindow >= d.ScanInterval {
+		return time.Duration(random.Int63n(int64(d.AdvertisingInterval)))
+	}
+
+	scanPhase := time.Duration(random.Int63n(int64(d.ScanInterval)))
+	advertisement := time.Duration(random.Int63n(int64(d.AdvertisingInterval)))
+	for {
+		if (advertisement+scanPhase)%d.ScanInterval < d.ScanWindow {
+			return advertisement
+		}
+		advertisement += d.AdvertisingInterval + time.Duration(random.Int63n(int64(maxAdvertisingDelay)))
+	}
+}
+
+// A discovery is a pair of nodes in range, which are not connected yet
+type discovery struct {
+	nodeA    *InternalNode
+	nodeB    *InternalNode
+	start    time.Duration // start is when the nodes came within range
+	time     time.Duration // time is when the nodes connect, after discovering each other and completing the handshake
+	fail     bool          // fail is whether the connection attempt fails
+	lifetime time.Duration // lifetime is how long the connection lasts before it drops by itself, if it is positive
+	event    *ConnectEvent // event is the scheduled connect event, if there is one
+}
+
+// startDiscovery returns the time at which the nodes connect, if they come within range at the given time and stay there.
+// If the nodes are already discovering each other, the time of the ongoing discovery is returned.
+func (s *Simulator) startDiscovery(nodeA *InternalNode, nodeB *InternalNode, start time.Duration) time.Duration {
+	if !s.tracksConnectionAttempts() {
+		return start
+	}
+
+	pair := newNodePair(nodeA, nodeB)
+	if d, found := s.discoveries[pair]; found {
+		return d.time
+	}
+
+	connectTime := start
+	if s.options.Discovery != nil {
+		connectTime += s.options.Discovery.DiscoveryDelay(nodeA.coords, nodeB.coords, s.random)
+	}
+	fail, handshake, lifetime := s.connectionAttempt(nodeA, nodeB)
+	connectTime += handshake
+
+	s.discoveries[pair] = &discovery{nodeA, nodeB, start, connectTime, fail, lifetime, nil}
+	return connectTime
+}
+
+// pushDiscoveryConnectEvent schedules the connect event of the discovery between the nodes, such that it is cancelled if the discovery is aborted
+func (s *Simulator) pushDiscoveryConnectEvent(time time.Duration, nodeA *InternalNode, nodeB *InternalNode) *ConnectEvent {
+	connectEvent := s.pushConnectEvent(time, nodeA, nodeB)
+	if d, found := s.discoveries[newNodePair(nodeA, nodeB)]; found {
+		d.event = connectEvent
+	}
+	return connectEvent
+}
+
+// completeDiscovery is called when the nodes attempt to connect, and returns the discovery if there is one
+func (s *Simulator) completeDiscovery(nodeA *InternalNode, nodeB *InternalNode) *discovery {
+	pair := newNodePair(nodeA, nodeB)
+	d := s.discoveries[pair]
+	delete(s.discoveries, pair)
+	return d
+}
+
+// abortDiscovery stops the discovery between the nodes, if there is one
+func (s *Simulator) abortDiscovery(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	d, found := s.discoveries[pair]
+	if !found {
+		return
+	}
+	delete(s.discoveries, pair)
+	if d.event != nil {
+		d.event.cancelled = true
+	}
+	if d.start <= s.time {
+		s.logDebug(fmt.Sprintf("simulator:discovery:missed:%d:%d", d.nodeA.nodeID, d.nodeB.nodeID))
+	}
+}
+
+// abortDiscoveriesOutOfRange is used when ContactPrediction is disabled, to abort the discoveries of nodes that have left range
+func (s *Simulator) abortDiscoveriesOutOfRange() {
+	pairs := []nodePair{}
+	for pair, d := range s.discoveries {
+		if !s.regionMap.withinContactRange(d.nodeA, d.nodeB) {
+			pairs = append(pairs, pair)
+		}
+	}
+	slices.SortFunc(pairs, func(a, b nodePair) int {
+		return cmp.Or(cmp.Compare(a.a, b.a), cmp.Compare(a.b, b.b))
+	})
+	for _, pair := range pairs {
+		d := s.discoveries[pair]
+		s.abortDiscovery(d.nodeA, d.nodeB)
+	}
+}
+
+func (s *Simulator) forgetDiscoveries(node *InternalNode) {
+	for pair, d := range s.discoveries {
+		if d.nodeA == node || d.nodeB == node {
+			delete(s.discoveries, pair)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"time"
+)
+
+// An EnergyProfile gives the energy costs of a device. The radio is charged for the bytes it transmits and receives,
+// and the node is charged for the time it spends connected to each peer and for the time it spends scanning,
+// which it does while it is running. When its battery runs out, the node is removed from the simulation.
+type EnergyProfile struct {
+	Capacity        float64 // The energy stored in a full battery in joules, the battery does not run out if it is 0
+	TransmitPerByte float64 // The energy in joules used to transmit a byte
+	ReceivePerByte  float64 // The energy in joules used to receive a byte
+	ConnectedPower  float64 // The power in watts used to maintain each connection
+	ScanPower       float64 // The power in watts used to scan for other nodes
+}
+
+// EnergyUsage is the energy in joules that a node has used for each of its activities
+type EnergyUsage struct {
+	Transmit  float64
+	Receive   float64
+	Connected float64
+	Scan      float64
+}
+
+func (u EnergyUsage) Total() float64 {
+	return u.Transmit + u.Receive + u.Connected + u.Scan
+}
+
+// energyState is the energy that a node has used, and the activities it was charged for when it was last updated
+type energyState struct {
+	profile     EnergyProfile
+	usage       EnergyUsage
+	updatedAt   time.Duration
+	started     bool
+	connections int
+	scanning    bool
+	depleted    bool
+	depletedAt  time.Duration
+	depletion   *DepleteEvent
+}
+
+func newEnergyState(profile *EnergyProfile) *energyState {
+	if profile == nil {
+		return nil
+	}
+	if profile.Capacity < 0 || profile.TransmitPerByte < 0 || profile.ReceivePerByte < 0 || profile.ConnectedPower < 0 || profile.ScanPower < 0 {
+		panic("energy profile must not be negative")
+	}
+	return &energyState{profile: *profile}
+}
+
+// usageAt returns the energy used until the given time, which must not be before the last update
+func (e *energyState) usageAt(t time.Duration) EnergyUsage {
+	usage := e.usage
+	elapsed := (t - e.updatedAt).Seconds()
+	usage.Connected += float64(e.connections) * e.profile.ConnectedPower * elapsed
+	if e.scanning {
+		usage.Scan += e.profile.ScanPower * elapsed
+	}
+	return usage
+}
+
+func (e *energyState) power() float64 {
+	power := float64(e.connections) * e.profile.ConnectedPower
+	if e.scanning {
+		power += e.profile.ScanPower
+	}
+	return power
+}
+
+// EnergyProfile returns the energy profile of the node, if it has one
+func (n *InternalNode) EnergyProfile() (EnergyProfile, bool) {
+	if n.energy == nil {
+		return EnergyProfile{}, false
+	}
+	return n.energy.profile, true
+}
+
+// EnergyUsage returns the energy that the node has used until now, if it has an energy profile
+func (n *InternalNode) EnergyUsage() (EnergyUsage, bool) {
+	if n.energy == nil {
+		return EnergyUsage{}, false
+	}
+	return n.energy.usageAt(n.sim.time), true
+}
+
+// DepletedAt returns the time at which the battery of the node ran out, if it has
+func (n *InternalNode) DepletedAt() (time.Duration, bool) {
+	if n.energy == nil || !n.energy.depleted {
+		return 0, false
+	}
+	return n.energy.depletedAt, true
+}
+
+// startEnergy starts charging the node for scanning when it is added to the simulation
+func (s *Simulator) startEnergy(iNode *InternalNode) {
+	if iNode.energy == nil {
+		return
+	}
+	iNode.energy.started = true
+	s.updateEnergy(iNode)
+}
+
+// chargeBytes charges the radio of the node for transmitting or receiving a packet of the given size
+func (s *Simulator) chargeBytes(iNode *InternalNode, size int, transmit bool) {
+	if iNode.energy == nil {
+		return
+	}
+	if transmit {
+		iNode.energy.usage.Transmit += float64(size) * iNode.energy.profile.TransmitPerByte
+	} else {
+		iNode.energy.usage.Receive += float64(size) * iNode.energy.profile.ReceivePerByte
+	}
+	s.updateEnergy(iNode)
+}
+
+// updateEnergy charges the node for the time since it was last updated, and must be called whenever
+// its connections or its state change. It schedules the time at which the battery runs out.
+func (s *Simulator) updateEnergy(iNode *InternalNode) {
+	e := iNode.energy
+	if e == nil || e.depleted {
+		return
+	}
+	e.usage = e.usageAt(s.time)
+	e.updatedAt = s.time
+	e.connections = len(iNode.peers)
+	e.scanning = e.started && !iNode.removed && !iNode.crashed
+
+	depletion := time.Duration(-1)
+	if e.profile.Capacity > 0 && !iNode.removed {
+		remaining := e.profile.Capacity - e.usage.Total()
+		if remaining <= 0 {
+			depletion = s.time
+		} else if power := e.power(); power > 0 {
+			depletion = s.time + time.Duration(math.Min(remaining/power*float64(time.Second), math.MaxInt64/2))
+		}
+	}
+
+	if depletion < 0 {
+		if e.depletion != nil {
+			e.depletion.cancelled = true
+			e.depletion = nil
+		}
+		return
+	}
+	if e.depletion == nil {
+		e.depletion = s.pushDepleteEvent(depletion, iNode)
+	} else if e.depletion.time != depletion {
+		e.depletion.time = depletion
+		e.depletion.sequenceNumber = s.currentSequenceNumber
+		s.currentSequenceNumber++
+		heap.Fix(s.eventQueue, e.depletion.index)
+	}
+}
+
+// depleteNode removes the node when its battery runs out
+func (s *Simulator) depleteNode(iNode *InternalNode) {
+	e := iNode.energy
+	e.depletion = nil
+	if iNode.removed {
+		return
+	}
+	e.usage = e.usageAt(s.time)
+	e.updatedAt = s.time
+	e.connections = 0
+	e.scanning = false
+	e.depleted = true
+	e.depletedAt = s.time
+
+	s.logDebug(fmt.Sprintf("simulator:node:depleted:%d", iNode.nodeID))
+	s.removeNode(iNode)
+}
+
+func (s *Simulator) pushDepleteEvent(time time.Duration, node *InternalNode) *DepleteEvent {
+	event := &DepleteEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		node: node,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+	return event
+}
@@ -0,0 +1,40 @@
+package energy_profiles
+
+import "github.com/starling-protocol/simulator"
+
+// The profiles below are rough estimates for Bluetooth Low Energy, including the overhead of the operating system,
+// and should be adjusted to the devices that are being simulated.
+
+// Smartphone has a 4000 mAh battery at 3.85 V
+func Smartphone() simulator.EnergyProfile {
+	return simulator.EnergyProfile{
+		Capacity:        55_440,
+		TransmitPerByte: 0.3e-6,
+		ReceivePerByte:  0.25e-6,
+		ConnectedPower:  1.5e-3,
+		ScanPower:       15e-3,
+	}
+}
+
+// Wearable has a 300 mAh battery at 3.8 V and a more efficient radio
+func Wearable() simulator.EnergyProfile {
+	return simulator.EnergyProfile{
+		Capacity:        4_104,
+		TransmitPerByte: 0.15e-6,
+		ReceivePerByte:  0.12e-6,
+		ConnectedPower:  0.5e-3,
+		ScanPower:       5e-3,
+	}
+}
+
+// Profile returns the profile with the given name, which is one of smartphone and wearable
+func Profile(name string) (simulator.EnergyProfile, bool) {
+	switch name {
+	case "smartphone":
+		return Smartphone(), true
+	case "wearable":
+		return Wearable(), true
+	default:
+		return simulator.EnergyProfile{}, false
+	}
+}
@@ -0,0 +1,346 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var eventLogMagic = []byte("STEL")
+
+const eventLogVersion = 1
+
+// An EventLogWriter writes processed events to a compact binary log, which can be replayed using NewReplaySimulator.
+// Each record contains the event type, time, sequence number, node IDs and packet bytes of an event.
+// Each record also contains the positions of the nodes that moved since the last record.
+// Nodes are identified by their internal ID, which stays the same when a node changes its ID,
+// and the replayed nodes keep the ID they were added with.
+type EventLogWriter struct {
+	w   *bufio.Writer
+	buf []byte
+	// sim is the simulator of the logged nodes, which tracks the nodes that moved since the last record
+	sim           *Simulator
+	lastPositions map[*InternalNode]Coordinate
+	wroteHeader   bool
+}
+
+func NewEventLogWriter(w io.Writer) *EventLogWriter {
+	return &EventLogWriter{
+		w:             bufio.NewWriter(w),
+		buf:           []byte{},
+		sim:           nil,
+		lastPositions: make(map[*InternalNode]Coordinate),
+		wroteHeader:   false,
+	}
+}
+
+// Write appends the event to the log
+func (l *EventLogWriter) Write(e Event) error {
+	if !l.wroteHeader {
+		l.wroteHeader = true
+		if _, err := l.w.Write(eventLogMagic); err != nil {
+			return err
+		}
+		if err := l.w.WriteByte(eventLogVersion); err != nil {
+			return err
+		}
+	}
+
+	buf := l.buf[:0]
+	buf = append(buf, byte(e.EventType()))
+	buf = binary.AppendUvarint(buf, uint64(e.Time()))
+	buf = binary.AppendVarint(buf, e.SequenceNumber())
+	buf = l.appendMovedNodes(buf)
+
+	switch e.EventType() {
+	case TIMESTEP:
+	case MOVEMENT:
+		buf = binary.AppendVarint(buf, int64(e.(*MovementEvent).node.internalID))
+	case CONNECT:
+		connectEvent := e.(*ConnectEvent)
+		buf = binary.AppendVarint(buf, int64(connectEvent.nodeA.internalID))
+		buf = binary.AppendVarint(buf, int64(connectEvent.nodeB.internalID))
+	case DISCONNECT:
+		disconnectEvent := e.(*DisconnectEvent)
+		buf = binary.AppendVarint(buf, int64(disconnectEvent.nodeA.internalID))
+		buf = binary.AppendVarint(buf, int64(disconnectEvent.nodeB.internalID))
+	case ADD_NODE:
+		node := e.(*AddNodeEvent).node
+		l.sim = node.sim
+		l.lastPositions[node] = node.coords
+		buf = binary.AppendVarint(buf, int64(node.internalID))
+		buf = binary.AppendVarint(buf, int64(node.nodeID))
+		buf = appendCoordinate(buf, node.coords)
+	case RM_NODE:
+		node := e.(*RemoveNodeEvent).node
+		delete(l.lastPositions, node)
+		buf = binary.AppendVarint(buf, int64(node.internalID))
+	case SEND_MSG:
+		sendEvent := e.(*SendEvent)
+		buf = binary.AppendVarint(buf, int64(sendEvent.origin.internalID))
+		buf = binary.AppendVarint(buf, int64(sendEvent.target.internalID))
+		buf = binary.AppendVarint(buf, int64(sendEvent.targetNodeID))
+		if sendEvent.shouldBeDropped {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		buf = binary.AppendVarint(buf, int64(sendEvent.delay))
+		buf = appendBytes(buf, sendEvent.packet)
+	case RCV_MSG:
+		receiveEvent := e.(*ReceiveEvent)
+		buf = binary.AppendVarint(buf, int64(receiveEvent.target.internalID))
+		buf = binary.AppendVarint(buf, int64(receiveEvent.origin.internalID))
+		buf = binary.AppendVarint(buf, int64(receiveEvent.originNodeID))
+		buf = appendBytes(buf, receiveEvent.packet)
+	case DELAY:
+		buf = binary.AppendVarint(buf, int64(e.(*DelayEvent).node.internalID))
+	case PARTITION:
+		p := e.(*PartitionEvent).partition
+		buf = binary.AppendUvarint(buf, uint64(p.index))
+		buf = binary.AppendVarint(buf, int64(p.heal))
+		buf = binary.AppendUvarint(buf, uint64(len(p.partition.Groups)))
+		for _, group := range p.partition.Groups {
+			buf = binary.AppendUvarint(buf, uint64(len(group)))
+			for _, id := range group {
+				buf = binary.AppendVarint(buf, int64(id))
+			}
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(p.partition.Cuts)))
+		for _, cut := range p.partition.Cuts {
+			buf = appendCoordinate(buf, cut.From)
+			buf = appendCoordinate(buf, cut.To)
+		}
+	case HEAL:
+		buf = binary.AppendUvarint(buf, uint64(e.(*HealEvent).partition.index))
+	case CRASH:
+		crashEvent := e.(*CrashEvent)
+		buf = binary.AppendVarint(buf, int64(crashEvent.node.internalID))
+		buf = binary.AppendUvarint(buf, uint64(crashEvent.downtime))
+	case RESTART:
+		buf = binary.AppendVarint(buf, int64(e.(*RestartEvent).node.internalID))
+	case DEPLETE:
+		node := e.(*DepleteEvent).node
+		delete(l.lastPositions, node)
+		buf = binary.AppendVarint(buf, int64(node.internalID))
+	case BUFFER_DROP:
+		dropEvent := e.(*BufferDropEvent)
+		buf = binary.AppendVarint(buf, int64(dropEvent.origin.internalID))
+		buf = binary.AppendVarint(buf, int64(dropEvent.targetNodeID))
+		if dropEvent.queued {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		buf = appendBytes(buf, dropEvent.packet)
+	case TERMINATE:
+		terminateEvent := e.(*TerminateEvent)
+		message := ""
+		if terminateEvent.err != nil {
+			message = terminateEvent.err.Error()
+		}
+		buf = appendBytes(buf, []byte(message))
+	default:
+		return fmt.Errorf("cannot write event of type %s to event log", e.EventType())
+	}
+
+	l.buf = buf
+	_, err := l.w.Write(buf)
+	return err
+}
+
+// Flush writes any buffered records to the underlying writer
+func (l *EventLogWriter) Flush() error {
+	return l.w.Flush()
+}
+
+// appendMovedNodes appends the positions of the logged nodes that moved since the last record
+func (l *EventLogWriter) appendMovedNodes(buf []byte) []byte {
+	moved := []*InternalNode{}
+	if l.sim != nil {
+		for _, node := range l.sim.movedNodes {
+			if last, found := l.lastPositions[node]; found && last != node.coords {
+				moved = append(moved, node)
+			}
+		}
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(moved)))
+	for _, node := range moved {
+		l.lastPositions[node] = node.coords
+		buf = binary.AppendVarint(buf, int64(node.internalID))
+		buf = appendCoordinate(buf, node.coords)
+	}
+	return buf
+}
+
+func appendCoordinate(buf []byte, c Coordinate) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.X))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.Y))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.Z))
+	buf = binary.AppendVarint(buf, int64(c.Floor))
+	return buf
+}
+
+func appendBytes(buf []byte, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+type eventLogReader struct {
+	r *bufio.Reader
+}
+
+func (r *eventLogReader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(r.r)
+}
+
+func (r *eventLogReader) varint() (int64, error) {
+	return binary.ReadVarint(r.r)
+}
+
+func (r *eventLogReader) nodeID() (NodeID, error) {
+	id, err := r.varint()
+	return NodeID(id), err
+}
+
+func (r *eventLogReader) internalID() (InternalID, error) {
+	id, err := r.varint()
+	return InternalID(id), err
+}
+
+func (r *eventLogReader) coordinate() (Coordinate, error) {
+	var raw [24]byte
+	if _, err := io.ReadFull(r.r, raw[:]); err != nil {
+		return Coordinate{}, err
+	}
+	floor, err := r.varint()
+	if err != nil {
+		return Coordinate{}, err
+	}
+	return Coordinate{
+		X:     math.Float64frombits(binary.LittleEndian.Uint64(raw[0:8])),
+		Y:     math.Float64frombits(binary.LittleEndian.Uint64(raw[8:16])),
+		Z:     math.Float64frombits(binary.LittleEndian.Uint64(raw[16:24])),
+		Floor: int(floor),
+	}, nil
+}
+
+func (r *eventLogReader) bytes() ([]byte, error) {
+	length, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r.r, data)
+	return data, err
+}
+
+// partition reads a partition starting at the given time
+func (r *eventLogReader) partition(start time.Duration) (*scheduledPartition, error) {
+	index, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	heal, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	partition := Partition{}
+	groupCount, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for range groupCount {
+		size, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		group := []NodeID{}
+		for range size {
+			id, err := r.nodeID()
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, id)
+		}
+		partition.Groups = append(partition.Groups, group)
+	}
+	cutCount, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for range cutCount {
+		from, err := r.coordinate()
+		if err != nil {
+			return nil, err
+		}
+		to, err := r.coordinate()
+		if err != nil {
+			return nil, err
+		}
+		partition.Cuts = append(partition.Cuts, Cut{From: from, To: to})
+	}
+
+	groups, err := partitionGroups(partition)
+	if err != nil {
+		return nil, err
+	}
+	return &scheduledPartition{
+		partition: partition,
+		index:     int(index),
+		start:     start,
+		heal:      time.Duration(heal),
+		groups:    groups,
+	}, nil
+}
+
+func (r *eventLogReader) header() error {
+	var header [5]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return err
+	}
+	if string(header[:4]) != string(eventLogMagic) {
+		return errors.New("not an event log")
+	}
+	if header[4] != eventLogVersion {
+		return fmt.Errorf("unsupported event log version %d", header[4])
+	}
+	return nil
+}
+
+type replayPosition struct {
+	internalID InternalID
+	coords     Coordinate
+}
+
+func (r *eventLogReader) positions() ([]replayPosition, error) {
+	count, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	positions := []replayPosition{}
+	for i := uint64(0); i < count; i++ {
+		id, err := r.internalID()
+		if err != nil {
+			return nil, err
+		}
+		coords, err := r.coordinate()
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, replayPosition{id, coords})
+	}
+	return positions, nil
+}
+
+func (r *eventLogReader) duration() (time.Duration, error) {
+	t, err := r.uvarint()
+	return time.Duration(t), err
+}
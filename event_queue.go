@@ -17,17 +17,21 @@ func (pq EventQueue) Less(i, j int) bool {
 
 func (pq EventQueue) Swap(i, j int) {
 	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].base().index = i
+	pq[j].base().index = j
 }
 
 func (pq *EventQueue) Push(x any) {
-	item := x
-	*pq = append(*pq, item.(Event))
+	item := x.(Event)
+	item.base().index = len(*pq)
+	*pq = append(*pq, item)
 }
 
 func (pq *EventQueue) Pop() any {
 	old := *pq
 	n := len(old)
 	item := old[n-1]
+	item.base().index = -1
 	old[n-1] = nil // avoid memory leak
 	*pq = old[0 : n-1]
 	return item
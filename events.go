@@ -17,6 +17,13 @@ const (
 	RCV_MSG
 	DELAY
 	TERMINATE
+	MOVEMENT
+	PARTITION
+	HEAL
+	CRASH
+	RESTART
+	DEPLETE
+	BUFFER_DROP
 )
 
 func (e EventType) String() string {
@@ -27,6 +34,10 @@ func (e EventType) String() string {
 		return "connect"
 	case DISCONNECT:
 		return "disconnect"
+	case ADD_NODE:
+		return "add_node"
+	case RM_NODE:
+		return "rm_node"
 	case RCV_MSG:
 		return "rcv_msg"
 	case SEND_MSG:
@@ -35,6 +46,20 @@ func (e EventType) String() string {
 		return "delay"
 	case TERMINATE:
 		return "terminate"
+	case MOVEMENT:
+		return "movement"
+	case PARTITION:
+		return "partition"
+	case HEAL:
+		return "heal"
+	case CRASH:
+		return "crash"
+	case RESTART:
+		return "restart"
+	case DEPLETE:
+		return "deplete"
+	case BUFFER_DROP:
+		return "buffer_drop"
 	default:
 		return "unknown"
 	}
@@ -44,6 +69,10 @@ type BaseEvent struct {
 	time           time.Duration
 	sequenceNumber int64
 	parentEvent    Event
+	cancelled      bool
+	duplicate      bool // duplicate is set for copies of events pushed by an interceptor
+	intercepted    bool
+	index          int // index is the position of the event in the event queue
 }
 
 func (e *BaseEvent) Time() time.Duration {
@@ -58,11 +87,16 @@ func (e *BaseEvent) ParentEvent() Event {
 	return e.parentEvent
 }
 
+func (e *BaseEvent) base() *BaseEvent {
+	return e
+}
+
 type Event interface {
 	EventType() EventType
 	Time() time.Duration
 	SequenceNumber() int64
 	ParentEvent() Event
+	base() *BaseEvent
 }
 
 func NodeFromEvent(e Event) Node {
@@ -73,6 +107,12 @@ func NodeFromEvent(e Event) Node {
 	case DISCONNECT:
 		conn := e.(*DisconnectEvent)
 		return conn.nodeA.node
+	case ADD_NODE:
+		add := e.(*AddNodeEvent)
+		return add.node.node
+	case RM_NODE:
+		rm := e.(*RemoveNodeEvent)
+		return rm.node.node
 	case SEND_MSG:
 		send := e.(*SendEvent)
 		return send.origin.node
@@ -84,6 +124,17 @@ func NodeFromEvent(e Event) Node {
 		return delay.node.node
 	case TERMINATE:
 		return NodeFromEvent(e.ParentEvent())
+	case MOVEMENT:
+		movement := e.(*MovementEvent)
+		return movement.node.node
+	case CRASH:
+		return e.(*CrashEvent).node.node
+	case RESTART:
+		return e.(*RestartEvent).node.node
+	case DEPLETE:
+		return e.(*DepleteEvent).node.node
+	case BUFFER_DROP:
+		return e.(*BufferDropEvent).origin.node
 	default:
 		return nil
 	}
@@ -97,6 +148,19 @@ func (e *TimestepEvent) EventType() EventType {
 	return TIMESTEP
 }
 
+type MovementEvent struct {
+	BaseEvent
+	node *InternalNode
+}
+
+func (e *MovementEvent) EventType() EventType {
+	return MOVEMENT
+}
+
+func (e *MovementEvent) Node() *InternalNode {
+	return e.node
+}
+
 type DelayEvent struct {
 	BaseEvent
 	node           *InternalNode
@@ -212,6 +276,11 @@ func (e *SendEvent) Packet() []byte {
 	return e.packet
 }
 
+// SetPacket replaces the packet, e.g. to corrupt it in an interceptor
+func (e *SendEvent) SetPacket(packet []byte) {
+	e.packet = packet
+}
+
 func (e *SendEvent) TargetNodeID() NodeID {
 	return e.targetNodeID
 }
@@ -237,6 +306,11 @@ func (e *ReceiveEvent) Packet() []byte {
 	return e.packet
 }
 
+// SetPacket replaces the packet, e.g. to corrupt it in an interceptor
+func (e *ReceiveEvent) SetPacket(packet []byte) {
+	e.packet = packet
+}
+
 func (e *ReceiveEvent) OriginNodeID() NodeID {
 	return e.originNodeID
 }
@@ -245,6 +319,140 @@ func (e *ReceiveEvent) Target() *InternalNode {
 	return e.target
 }
 
+func (e *ReceiveEvent) Origin() *InternalNode {
+	return e.origin
+}
+
+// A PartitionEvent starts a partition scheduled with SchedulePartition
+type PartitionEvent struct {
+	BaseEvent
+	partition *scheduledPartition
+}
+
+func (e *PartitionEvent) EventType() EventType {
+	return PARTITION
+}
+
+func (e *PartitionEvent) Partition() Partition {
+	return e.partition.partition
+}
+
+// HealTime returns the time at which the partition heals
+func (e *PartitionEvent) HealTime() time.Duration {
+	return e.partition.heal
+}
+
+// A HealEvent heals a partition scheduled with SchedulePartition
+type HealEvent struct {
+	BaseEvent
+	partition *scheduledPartition
+}
+
+func (e *HealEvent) EventType() EventType {
+	return HEAL
+}
+
+func (e *HealEvent) Partition() Partition {
+	return e.partition.partition
+}
+
+// StartTime returns the time at which the partition started
+func (e *HealEvent) StartTime() time.Duration {
+	return e.partition.start
+}
+
+// A CrashEvent crashes a node, which restarts after the downtime
+type CrashEvent struct {
+	BaseEvent
+	node     *InternalNode
+	downtime time.Duration
+	random   bool // random is set for crashes scheduled by the crash model of the node
+}
+
+func (e *CrashEvent) EventType() EventType {
+	return CRASH
+}
+
+func (e *CrashEvent) Node() *InternalNode {
+	return e.node
+}
+
+func (e *CrashEvent) Downtime() time.Duration {
+	return e.downtime
+}
+
+type RestartEvent struct {
+	BaseEvent
+	node *InternalNode
+}
+
+func (e *RestartEvent) EventType() EventType {
+	return RESTART
+}
+
+func (e *RestartEvent) Node() *InternalNode {
+	return e.node
+}
+
+// A DepleteEvent removes a node from the simulation when its battery runs out
+type DepleteEvent struct {
+	BaseEvent
+	node *InternalNode
+}
+
+func (e *DepleteEvent) EventType() EventType {
+	return DEPLETE
+}
+
+func (e *DepleteEvent) Node() *InternalNode {
+	return e.node
+}
+
+// A BufferDropEvent is passed to the loggers when a packet is dropped because the buffer of its origin is congested.
+// It is not part of the event queue. Queued tells whether the queue discipline dropped a queued packet to make room
+// for another packet, otherwise the packet was not admitted to the buffer.
+type BufferDropEvent struct {
+	BaseEvent
+	origin       *InternalNode
+	targetNodeID NodeID
+	packet       []byte
+	queued       bool
+}
+
+func (s *Simulator) newBufferDropEvent(origin *InternalNode, targetNodeID NodeID, packet []byte, queued bool) *BufferDropEvent {
+	return &BufferDropEvent{
+		BaseEvent: BaseEvent{
+			time:           s.time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		origin:       origin,
+		targetNodeID: targetNodeID,
+		packet:       packet,
+		queued:       queued,
+	}
+}
+
+func (e *BufferDropEvent) EventType() EventType {
+	return BUFFER_DROP
+}
+
+func (e *BufferDropEvent) Origin() *InternalNode {
+	return e.origin
+}
+
+func (e *BufferDropEvent) TargetNodeID() NodeID {
+	return e.targetNodeID
+}
+
+func (e *BufferDropEvent) Packet() []byte {
+	return e.packet
+}
+
+func (e *BufferDropEvent) Queued() bool {
+	return e.queued
+}
+
 type TerminateEvent struct {
 	BaseEvent
 	err error
@@ -270,7 +478,20 @@ func (s *Simulator) pushTimeStepEvent(time time.Duration) {
 	s.currentSequenceNumber++
 }
 
-func (s *Simulator) pushConnectEvent(time time.Duration, nodeA *InternalNode, nodeB *InternalNode) {
+func (s *Simulator) pushMovementEvent(time time.Duration, node *InternalNode) {
+	event := &MovementEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		node: node,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+}
+
+func (s *Simulator) pushConnectEvent(time time.Duration, nodeA *InternalNode, nodeB *InternalNode) *ConnectEvent {
 	event := &ConnectEvent{
 		BaseEvent: BaseEvent{
 			time:           time,
@@ -282,9 +503,10 @@ func (s *Simulator) pushConnectEvent(time time.Duration, nodeA *InternalNode, no
 	}
 	heap.Push(s.eventQueue, event)
 	s.currentSequenceNumber++
+	return event
 }
 
-func (s *Simulator) pushDisconnectEvent(time time.Duration, nodeA *InternalNode, nodeB *InternalNode) {
+func (s *Simulator) pushDisconnectEvent(time time.Duration, nodeA *InternalNode, nodeB *InternalNode) *DisconnectEvent {
 	event := &DisconnectEvent{
 		BaseEvent: BaseEvent{
 			time:           time,
@@ -296,6 +518,7 @@ func (s *Simulator) pushDisconnectEvent(time time.Duration, nodeA *InternalNode,
 	}
 	heap.Push(s.eventQueue, event)
 	s.currentSequenceNumber++
+	return event
 }
 
 func (s *Simulator) pushAddNodeEvent(time time.Duration, node *InternalNode) {
@@ -324,7 +547,7 @@ func (s *Simulator) pushRemoveNodeEvent(time time.Duration, node *InternalNode)
 	s.currentSequenceNumber++
 }
 
-func (s *Simulator) pushReceiveEvent(time time.Duration, target *InternalNode, origin *InternalNode, originNodeID NodeID, peer Peer, packet []byte) {
+func (s *Simulator) pushReceiveEvent(time time.Duration, target *InternalNode, origin *InternalNode, originNodeID NodeID, peer Peer, packet []byte) *ReceiveEvent {
 	event := &ReceiveEvent{
 		BaseEvent: BaseEvent{
 			time:           time,
@@ -339,12 +562,14 @@ func (s *Simulator) pushReceiveEvent(time time.Duration, target *InternalNode, o
 	}
 	heap.Push(s.eventQueue, event)
 	s.currentSequenceNumber++
+	return event
 }
 
-func (s *Simulator) pushSendEvent(time time.Duration, target *InternalNode, origin *InternalNode, targetNodeID NodeID, peer Peer, shouldBeDropped bool, delay time.Duration, packet []byte) {
+// newSendEvent creates a send event for a packet entering the buffer of its origin, it is pushed when the packet is scheduled
+func (s *Simulator) newSendEvent(target *InternalNode, origin *InternalNode, targetNodeID NodeID, peer Peer, shouldBeDropped bool, delay time.Duration, packet []byte) *SendEvent {
 	event := &SendEvent{
 		BaseEvent: BaseEvent{
-			time:           time,
+			time:           0,
 			sequenceNumber: s.currentSequenceNumber,
 			parentEvent:    s.lastEvent,
 		},
@@ -356,8 +581,8 @@ func (s *Simulator) pushSendEvent(time time.Duration, target *InternalNode, orig
 		delay:           delay,
 		packet:          packet,
 	}
-	heap.Push(s.eventQueue, event)
 	s.currentSequenceNumber++
+	return event
 }
 
 func (s *Simulator) pushDelayEvent(time time.Duration, node *InternalNode, functionToCall func()) {
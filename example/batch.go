@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/batch"
+	"github.com/starling-protocol/simulator/loggers"
+	"github.com/starling-protocol/simulator/movement_profiles"
+	node "github.com/starling-protocol/simulator/starling_node"
+	"github.com/starling-protocol/simulator/transmission_behavior"
+)
+
+func batchExample() {
+	factory := func(seed int64) *simulator.Simulator {
+		random := rand.New(rand.NewSource(seed))
+
+		var transmissionBehavior = transmission_behavior.RandomDrops{
+			DropChance: 0.01,
+			Delay:      20 * time.Millisecond,
+			Random:     random,
+		}
+
+		var movementProfile = movement_profiles.NewRandomNode(100, 100, 60, 20, random)
+
+		statisticsLogger := &loggers.StatisticsLogger{Silent: true}
+		var sim = simulator.NewSimulator(20.0, 20*time.Millisecond, random, []simulator.Logger{statisticsLogger}, nil)
+
+		nodes := []*node.Node{}
+		for i := 0; i < 50; i++ {
+			nodeId := (simulator.NodeID)(int64(i))
+			n := node.NewNode(random, &nodeId, transmissionBehavior, nil)
+			sim.AddNode(n, movementProfile, 0)
+			nodes = append(nodes, n)
+		}
+
+		contact := node.LinkNodes(nodes[0], nodes[1])
+		orchestrator := node.NewSessionOrchestrator(contact)
+		sessionA := orchestrator.SessionScenario(true)
+		nodes[0].AddScenario(sessionA)
+		sessionB := orchestrator.SessionScenario(true)
+		nodes[1].AddScenario(sessionB)
+		nodes[0].AddScenario(sessionA.SendDataScenario("ping"))
+
+		return sim
+	}
+
+	options := batch.DefaultOptions()
+	options.Duration = 2 * time.Minute
+
+	result := batch.Run(factory, batch.Seeds(1, 16), options)
+	result.Print(os.Stdout)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/bridge_node"
+	"github.com/starling-protocol/simulator/loggers"
+	"github.com/starling-protocol/simulator/movement_profiles"
+	node "github.com/starling-protocol/simulator/starling_node"
+	"github.com/starling-protocol/simulator/transmission_behavior"
+)
+
+// bridgeExample runs a crowd in real time around a bridge node, which an external Starling build can attach to
+// by listening on 127.0.0.1:47001 and sending its packets to 127.0.0.1:47000
+func bridgeExample() error {
+	seed := 20 //rand.Int63()
+	fmt.Printf("Seed: %d\n", seed)
+	random := rand.New(rand.NewSource(int64(seed)))
+
+	var transmissionBehavior = transmission_behavior.RandomDrops{
+		DropChance: 0.01,
+		Delay:      time.Duration(20) * time.Millisecond,
+		Random:     random,
+	}
+
+	var movementProfile = movement_profiles.NewRandomNode(50, 50, 60, 20, random)
+
+	var loggerList []simulator.Logger
+	logger := loggers.NewStandardLogger()
+	loggerList = append(loggerList, logger)
+
+	var sim = simulator.NewSimulator(20.0, time.Duration(20*time.Millisecond), random, loggerList, nil)
+
+	for i := 1; i <= 20; i++ {
+		nodeId := (simulator.NodeID)(int64(i))
+		n := node.NewNode(random, &nodeId, transmissionBehavior, nil)
+		sim.AddNode(n, movementProfile, 0)
+	}
+
+	bridge, err := bridge_node.NewNode(0, "udp", "127.0.0.1:47000", "127.0.0.1:47001", transmissionBehavior, nil)
+	if err != nil {
+		return err
+	}
+	sim.AddNode(bridge, movement_profiles.NewStationary(25, 25), 0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	options := simulator.DefaultRunOptions()
+	options.Until = 10 * time.Minute
+	options.Speed = 1
+	options.OnStall = func(s *simulator.Simulator, lag time.Duration) {
+		fmt.Printf("Simulation is %s behind the wall clock\n", lag)
+	}
+	result := sim.Run(ctx, options)
+	fmt.Printf("Stopped at %s: %s\n", result.Time, result.Reason)
+	if sim.IsRunning() {
+		sim.Terminate()
+	}
+	if result.Reason == simulator.StopCancelled {
+		return nil
+	}
+	return result.Err
+}
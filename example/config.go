@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/scenario_config"
+	"github.com/starling-protocol/simulator/visualizer"
+)
+
+func configExample(path string) error {
+	config, err := scenario_config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	simulation, err := config.Build()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Seed: %d\n", simulation.Seed)
+
+	sim := simulation.Simulator
+	if config.Duration <= 0 {
+		speed := 1.0
+		if config.Speed > 0 {
+			speed = config.Speed
+		}
+		visualizer.StartGUI(sim, false, speed, config.Topography)
+		return nil
+	}
+
+	result := sim.Run(context.Background(), simulation.RunOptions)
+	if result.Reason != simulator.StopUntil && result.Reason != simulator.StopTerminated {
+		fmt.Printf("Stopped at %s: %s\n", result.Time, result.Reason)
+	}
+	if result.Stalls > 0 {
+		fmt.Printf("Fell behind the wall clock %d times, by up to %s\n", result.Stalls, result.MaxLag)
+	}
+	if sim.IsRunning() {
+		sim.Terminate()
+	}
+	return result.Err
+}
@@ -11,6 +11,8 @@ import (
 
 func main() {
 	var example string
+	var replay string
+	var config string
 
 	app := &cli.App{
 		Name:  "examples",
@@ -20,11 +22,31 @@ func main() {
 				Name:        "example",
 				Aliases:     []string{"e"},
 				Usage:       "Name of example",
-				Required:    true,
 				Destination: &example,
 			},
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"c"},
+				Usage:       "Path of a JSON or YAML file describing the simulation",
+				Destination: &config,
+			},
+			&cli.StringFlag{
+				Name:        "replay",
+				Aliases:     []string{"r"},
+				Usage:       "Path of an event log to replay",
+				Destination: &replay,
+			},
 		},
 		Action: func(cCtx *cli.Context) error {
+			if config != "" {
+				return configExample(config)
+			}
+			if replay != "" {
+				return replayExample(replay)
+			}
+			if example == "" {
+				return errors.New("either an example name, a config file or an event log to replay must be given")
+			}
 			return runExample(strings.ToLower(example))
 		},
 	}
@@ -46,6 +68,12 @@ func runExample(example string) error {
 		highTrafficExample()
 	case "sync":
 		syncExample()
+	case "batch":
+		batchExample()
+	case "sweep":
+		sweepExample()
+	case "bridge":
+		return bridgeExample()
 	default:
 		return errors.New("given example name does not exist")
 	}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/loggers"
+	"github.com/starling-protocol/simulator/visualizer"
+)
+
+func replayExample(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var loggerList []simulator.Logger
+	statisticsLogger := loggers.NewStatisticsLogger()
+	loggerList = append(loggerList, statisticsLogger)
+
+	sim, err := simulator.NewReplaySimulator(file, loggerList)
+	if err != nil {
+		return err
+	}
+
+	visualizer.StartGUI(sim, false, 1, "")
+	return nil
+}
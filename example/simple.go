@@ -37,7 +37,7 @@ func simpleExample() {
 	pcapLogger := &loggers.PCAPLogger{}
 	loggerList = append(loggerList, pcapLogger)
 
-	var sim = simulator.NewSimulator(20.0, 20, random, loggerList)
+	var sim = simulator.NewSimulator(20.0, 20, random, loggerList, nil)
 
 	nodes := []*node.Node{}
 
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/batch"
+	"github.com/starling-protocol/simulator/loggers"
+	"github.com/starling-protocol/simulator/movement_profiles"
+	node "github.com/starling-protocol/simulator/starling_node"
+	"github.com/starling-protocol/simulator/transmission_behavior"
+	"github.com/starling-protocol/starling/device"
+)
+
+func sweepExample() {
+	factory := func(params batch.Params, seed int64) *simulator.Simulator {
+		random := rand.New(rand.NewSource(seed))
+
+		var transmissionBehavior = transmission_behavior.RandomDrops{
+			DropChance: params.Float(batch.DropChance),
+			Delay:      20 * time.Millisecond,
+			Random:     random,
+		}
+
+		var movementProfile = movement_profiles.NewRandomNode(100, 100, 60, 20, random)
+		protocolOptions := params.ProtocolOptions(nil)
+
+		statisticsLogger := &loggers.StatisticsLogger{Silent: true}
+		var sim = simulator.NewSimulator(params.Float(batch.BLERange), 20*time.Millisecond, random, []simulator.Logger{statisticsLogger}, nil)
+
+		nodes := []*node.Node{}
+		for i := 0; i < params.Int(batch.NodeCount); i++ {
+			nodeId := (simulator.NodeID)(int64(i))
+			n := node.NewNode(random, &nodeId, transmissionBehavior, protocolOptions)
+			sim.AddNode(n, movementProfile, 0)
+			nodes = append(nodes, n)
+		}
+
+		contact := node.LinkNodes(nodes[0], nodes[1])
+		orchestrator := node.NewSessionOrchestrator(contact)
+		sessionA := orchestrator.SessionScenario(true)
+		nodes[0].AddScenario(sessionA)
+		sessionB := orchestrator.SessionScenario(true)
+		nodes[1].AddScenario(sessionB)
+		nodes[0].AddScenario(sessionA.SendDataScenario("ping"))
+
+		return sim
+	}
+
+	parameters := []batch.Parameter{
+		batch.Floats(batch.BLERange, 15, 25),
+		batch.Ints(batch.NodeCount, 30, 60),
+		batch.Floats(batch.DropChance, 0.0, 0.1),
+		batch.Values(batch.ProtocolOptionPrefix+"RREQBroadcastStrategy", device.BroadcastAll, device.BroadcastLogFunc),
+	}
+
+	options := batch.DefaultOptions()
+	options.Duration = 1 * time.Minute
+
+	result := batch.Sweep(factory, parameters, batch.Seeds(1, 8), options)
+	result.Print(os.Stdout, "packages_sent", "metadata_packages", "sess_packets")
+}
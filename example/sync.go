@@ -39,7 +39,7 @@ func syncExample() {
 	syncLogger := &loggers.SyncLogger{}
 	loggerList = append(loggerList, syncLogger)
 
-	var sim = simulator.NewSimulator(20.0, time.Duration(20*time.Millisecond), random, loggerList)
+	var sim = simulator.NewSimulator(20.0, time.Duration(20*time.Millisecond), random, loggerList, nil)
 
 	protocolOptions := device.DefaultSyncProtocolOptions()
 
@@ -34,7 +34,7 @@ func vaderExample() {
 	profileLogger := &loggers.ProfileLogger{}
 	loggerList = append(loggerList, profileLogger)
 
-	var sim = simulator.NewSimulator(20.0, time.Duration(20*time.Millisecond), random, loggerList)
+	var sim = simulator.NewSimulator(20.0, time.Duration(20*time.Millisecond), random, loggerList, nil)
 
 	vadereNodeMap := vadere.VadereLoad("../vadere/old/postvis.traj", 0.1, random)
 	nodes := []*node.Node{}
@@ -0,0 +1,170 @@
+package simulator
+
+import (
+	"math"
+	"time"
+)
+
+// A GeoCoordinate is a WGS84 position given in degrees, with the altitude in metres
+type GeoCoordinate struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// A Projection maps geographic coordinates to the metric plane used by the simulator and back again.
+// X points east and Y points north, and the altitude is kept as Z.
+type Projection interface {
+	Project(GeoCoordinate) Coordinate
+	Unproject(Coordinate) GeoCoordinate
+}
+
+// earthRadius is the mean radius of the earth in metres
+const earthRadius = 6_371_008.8
+
+// Equirectangular projects positions onto a plane through Origin, which places Origin at 0, 0.
+// It is accurate to within a few metres for areas of a few kilometres around the origin.
+type Equirectangular struct {
+	Origin GeoCoordinate
+}
+
+func (p Equirectangular) Project(g GeoCoordinate) Coordinate {
+	cosOrigin := math.Cos(radians(p.Origin.Latitude))
+	return Coordinate{
+		X: earthRadius * radians(g.Longitude-p.Origin.Longitude) * cosOrigin,
+		Y: earthRadius * radians(g.Latitude-p.Origin.Latitude),
+		Z: g.Altitude,
+	}
+}
+
+func (p Equirectangular) Unproject(c Coordinate) GeoCoordinate {
+	cosOrigin := math.Cos(radians(p.Origin.Latitude))
+	return GeoCoordinate{
+		Latitude:  p.Origin.Latitude + degrees(c.Y/earthRadius),
+		Longitude: p.Origin.Longitude + degrees(c.X/(earthRadius*cosOrigin)),
+		Altitude:  c.Z,
+	}
+}
+
+// UTM projects positions to eastings and northings in a zone of the Universal Transverse Mercator system,
+// which is accurate to within millimetres inside the zone.
+type UTM struct {
+	// Zone is the zone number from 1 to 60
+	Zone int
+	// South selects the southern hemisphere, where northings start at 10000 km at the equator
+	South bool
+}
+
+// NewUTM returns the UTM projection of the zone containing the given position
+func NewUTM(g GeoCoordinate) UTM {
+	zone := int(math.Floor((g.Longitude+180)/6)) + 1
+	return UTM{Zone: max(1, min(60, zone)), South: g.Latitude < 0}
+}
+
+// Parameters of the WGS84 ellipsoid and the UTM system
+const (
+	wgs84SemiMajorAxis = 6_378_137.0
+	wgs84Flattening    = 1 / 298.257223563
+	utmScale           = 0.9996
+	utmFalseEasting    = 500_000.0
+	utmFalseNorthing   = 10_000_000.0
+)
+
+// krugerSeries contains the coefficients of the Krüger series for the transverse Mercator projection of an ellipsoid
+type krugerSeries struct {
+	rectifyingRadius float64
+	// eccentricity is the first eccentricity of the ellipsoid
+	eccentricity float64
+	alpha        [3]float64
+	beta         [3]float64
+	delta        [3]float64
+}
+
+func newKrugerSeries(semiMajorAxis float64, flattening float64) krugerSeries {
+	n := flattening / (2 - flattening)
+	n2, n3 := n*n, n*n*n
+	return krugerSeries{
+		rectifyingRadius: semiMajorAxis / (1 + n) * (1 + n2/4 + n2*n2/64),
+		eccentricity:     2 * math.Sqrt(n) / (1 + n),
+		alpha:            [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240},
+		beta:             [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480},
+		delta:            [3]float64{2*n - 2*n2/3 - 2*n3, 7*n2/3 - 8*n3/5, 56 * n3 / 15},
+	}
+}
+
+var wgs84Series = newKrugerSeries(wgs84SemiMajorAxis, wgs84Flattening)
+
+func (p UTM) centralMeridian() float64 {
+	return radians(float64(p.Zone*6 - 183))
+}
+
+func (p UTM) falseNorthing() float64 {
+	if p.South {
+		return utmFalseNorthing
+	}
+	return 0
+}
+
+func (p UTM) Project(g GeoCoordinate) Coordinate {
+	e := wgs84Series.eccentricity
+	sinLatitude := math.Sin(radians(g.Latitude))
+	longitude := radians(g.Longitude) - p.centralMeridian()
+
+	t := math.Sinh(math.Atanh(sinLatitude) - e*math.Atanh(e*sinLatitude))
+	xi := math.Atan2(t, math.Cos(longitude))
+	eta := math.Atanh(math.Sin(longitude) / math.Sqrt(1+t*t))
+
+	easting, northing := eta, xi
+	for j, alpha := range wgs84Series.alpha {
+		k := 2 * float64(j+1)
+		easting += alpha * math.Cos(k*xi) * math.Sinh(k*eta)
+		northing += alpha * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+
+	return Coordinate{
+		X: utmFalseEasting + utmScale*wgs84Series.rectifyingRadius*easting,
+		Y: p.falseNorthing() + utmScale*wgs84Series.rectifyingRadius*northing,
+		Z: g.Altitude,
+	}
+}
+
+func (p UTM) Unproject(c Coordinate) GeoCoordinate {
+	xi := (c.Y - p.falseNorthing()) / (utmScale * wgs84Series.rectifyingRadius)
+	eta := (c.X - utmFalseEasting) / (utmScale * wgs84Series.rectifyingRadius)
+
+	xiPrime, etaPrime := xi, eta
+	for j, beta := range wgs84Series.beta {
+		k := 2 * float64(j+1)
+		xiPrime -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+	latitude := chi
+	for j, delta := range wgs84Series.delta {
+		latitude += delta * math.Sin(2*float64(j+1)*chi)
+	}
+	longitude := p.centralMeridian() + math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime))
+
+	return GeoCoordinate{
+		Latitude:  degrees(latitude),
+		Longitude: degrees(longitude),
+		Altitude:  c.Z,
+	}
+}
+
+// NewGeoMovementInstruction returns an instruction to move to the projection of the given position
+func NewGeoMovementInstruction(projection Projection, g GeoCoordinate, time time.Duration) MovementInstruction {
+	return MovementInstruction{
+		Coords: projection.Project(g),
+		Time:   time,
+	}
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,170 @@
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// An Interceptor is called before the simulator dispatches an event, and can inspect, drop, delay, duplicate or rewrite it
+// through the interception, e.g. to inject faults. Interceptors are not called for the TIMESTEP, MOVEMENT and TERMINATE events
+// which drive the simulation itself, nor when an event log is replayed.
+type Interceptor interface {
+	Intercept(event Event, interception *Interception)
+}
+
+// InterceptorFunc adapts a function to an Interceptor
+type InterceptorFunc func(event Event, interception *Interception)
+
+func (f InterceptorFunc) Intercept(event Event, interception *Interception) {
+	f(event, interception)
+}
+
+// An Interception decides what happens to an intercepted event.
+// Events that are delayed or duplicated are not intercepted again when they are dispatched.
+type Interception struct {
+	sim     *Simulator
+	event   Event
+	dropped bool
+	delay   time.Duration
+}
+
+// Drop discards the event instead of dispatching it. Dropped packets are lost, a dropped connect event fails like
+// a failed connection attempt, such that the nodes attempt to connect again, and a dropped delay event does not call its function.
+// Disconnect events and the addition and removal of nodes cannot be dropped.
+func (i *Interception) Drop() {
+	switch i.event.EventType() {
+	case CONNECT, SEND_MSG, RCV_MSG, DELAY:
+		i.dropped = true
+	default:
+		panic(fmt.Sprintf("cannot drop %s event", i.event.EventType()))
+	}
+}
+
+// Delay postpones the event by delay. Only packets and delay events can be delayed.
+func (i *Interception) Delay(delay time.Duration) {
+	i.checkTiming(delay, "delay")
+	i.delay += delay
+}
+
+// Duplicate dispatches a copy of the event delay after it. A duplicated packet is transmitted or delivered again
+// without taking up space in the buffer of its origin, and a duplicated delay event calls its function again.
+// Only packets and delay events can be duplicated.
+func (i *Interception) Duplicate(delay time.Duration) {
+	i.checkTiming(delay, "duplicate")
+	i.sim.pushDuplicateEvent(i.event, delay)
+}
+
+// Dropped reports whether an earlier interceptor dropped the event
+func (i *Interception) Dropped() bool {
+	return i.dropped
+}
+
+func (i *Interception) checkTiming(delay time.Duration, action string) {
+	switch i.event.EventType() {
+	case SEND_MSG, RCV_MSG, DELAY:
+	default:
+		panic(fmt.Sprintf("cannot %s %s event", action, i.event.EventType()))
+	}
+	if delay < 0 {
+		panic("interception delay must not be negative")
+	}
+}
+
+// AddInterceptor registers an interceptor, which is called after the interceptors registered before it
+func (s *Simulator) AddInterceptor(interceptor Interceptor) {
+	s.interceptors = append(s.interceptors, interceptor)
+}
+
+// intercept passes the event to the interceptors, and reports whether it should be dispatched now
+func (s *Simulator) intercept(e Event) bool {
+	if len(s.interceptors) == 0 || e.base().intercepted {
+		return true
+	}
+	switch e.EventType() {
+	case TIMESTEP, MOVEMENT, TERMINATE:
+		return true
+	}
+
+	e.base().intercepted = true
+	interception := &Interception{sim: s, event: e}
+	for _, interceptor := range s.interceptors {
+		interceptor.Intercept(e, interception)
+		if interception.dropped {
+			s.dropIntercepted(e)
+			return false
+		}
+	}
+
+	if interception.delay > 0 {
+		e.base().time += interception.delay
+		e.base().sequenceNumber = s.currentSequenceNumber
+		s.currentSequenceNumber++
+		heap.Push(s.eventQueue, e)
+		return false
+	}
+	return true
+}
+
+// dropIntercepted cleans up after an event that an interceptor dropped, as if it had been dispatched without effect
+func (s *Simulator) dropIntercepted(e Event) {
+	switch e.EventType() {
+	case CONNECT:
+		connectEvent := e.(*ConnectEvent)
+		s.logDebug(fmt.Sprintf("simulator:intercept:drop:connect:%d:%d", connectEvent.nodeA.nodeID, connectEvent.nodeB.nodeID))
+		s.completeDiscovery(connectEvent.nodeA, connectEvent.nodeB)
+		if !s.options.ContactPrediction && !s.tracksConnectionAttempts() {
+			s.peers.remove(connectEvent.nodeA, connectEvent.nodeB)
+		}
+		s.reconnect(connectEvent.nodeA, connectEvent.nodeB)
+	case SEND_MSG:
+		sendEvent := e.(*SendEvent)
+		s.logDebug(fmt.Sprintf("simulator:intercept:drop:send_msg:%d:%d", sendEvent.origin.nodeID, sendEvent.target.nodeID))
+		if !sendEvent.duplicate {
+			sendEvent.origin.dequeue(sendEvent)
+			sendEvent.origin.curBufferCount--
+			if len(sendEvent.origin.queue) > 0 {
+				s.scheduleSend(sendEvent.origin)
+			}
+		}
+	case RCV_MSG:
+		receiveEvent := e.(*ReceiveEvent)
+		s.logDebug(fmt.Sprintf("simulator:intercept:drop:rcv_msg:%d:%d", receiveEvent.origin.nodeID, receiveEvent.target.nodeID))
+		if !receiveEvent.duplicate {
+			receiveEvent.origin.curBufferCount--
+		}
+	case DELAY:
+		s.logDebug(fmt.Sprintf("simulator:intercept:drop:delay:%d", e.(*DelayEvent).node.nodeID))
+	}
+}
+
+// pushDuplicateEvent pushes a copy of the event delay after it, which does not take up space in the buffer of its origin
+func (s *Simulator) pushDuplicateEvent(e Event, delay time.Duration) {
+	base := BaseEvent{
+		time:           e.Time() + delay,
+		sequenceNumber: s.currentSequenceNumber,
+		parentEvent:    e,
+		duplicate:      true,
+		intercepted:    true,
+	}
+
+	var duplicate Event
+	switch original := e.(type) {
+	case *SendEvent:
+		event := *original
+		event.BaseEvent = base
+		duplicate = &event
+	case *ReceiveEvent:
+		event := *original
+		event.BaseEvent = base
+		duplicate = &event
+	case *DelayEvent:
+		event := *original
+		event.BaseEvent = base
+		duplicate = &event
+	default:
+		panic(fmt.Sprintf("cannot duplicate %s event", e.EventType()))
+	}
+	heap.Push(s.eventQueue, duplicate)
+	s.currentSequenceNumber++
+}
@@ -2,6 +2,7 @@ package simulator
 
 import (
 	"math/rand"
+	"slices"
 	"time"
 
 	"github.com/starling-protocol/starling/utils"
@@ -13,26 +14,58 @@ type InternalNode struct {
 	internalID          InternalID
 	coords              Coordinate
 	movementInstruction MovementInstruction
+	segmentStart        Coordinate
+	segmentStartTime    time.Duration
+	segmentEndTime      time.Duration
 	nodeMovement        NodeMovement
 	peers               map[InternalID]Peer
+	connectedAt         map[InternalID]time.Duration // connectedAt contains the time at which each peer was connected
+	maxConnections      int
+	peerSelection       PeerSelection
 	random              *rand.Rand
 	sim                 *Simulator
 	curBufferCount      int
 	bufferSize          int
+	queueDiscipline     QueueDiscipline
+	queue               []*SendEvent // queue contains the packets waiting to be transmitted, in the order they are sent, only the first is scheduled
+	bandwidth           BandwidthModel
+	transmitFactor      float64 // transmitFactor scales the range of packets sent by the node, according to its transmit power
+	receiveFactor       float64 // receiveFactor scales the range of packets received by the node, according to its receiver sensitivity
 	lastMessageSent     time.Duration
+	lastMessageReceived time.Duration
 	data                map[string]interface{}
+	removed             bool
+	crashed             bool
+	restarts            int
+	crashModel          CrashModel
+	persistState        bool
+	nextCrash           *CrashEvent // nextCrash is the pending crash scheduled by the crash model
+	energy              *energyState
+	clock               Clock
+	dirty               bool // dirty is set when the connections of the node must be updated at the next time step
+	moved               bool // moved is set when the node is in the moved nodes of the simulator, see Simulator.markMoved
 }
 
 type InternalID int
 
+func (n *InternalNode) dequeue(sendEvent *SendEvent) {
+	if i := slices.Index(n.queue, sendEvent); i >= 0 {
+		n.queue = slices.Delete(n.queue, i, i+1)
+	}
+}
+
 type NodeArguments struct {
 	Data      func() *map[string]interface{}
 	UpdateID  func(newId NodeID)
-	DelayBy   func(func(), time.Duration)
-	Now       func() time.Time
+	DelayBy   func(func(), time.Duration) // DelayBy measures the delay by the local clock of the node, see Clock
+	Now       func() time.Time            // Now returns the time of the local clock of the node
 	Terminate func(error)
 	Log       func(message string)
 	Loggers   []Logger
+	// Restarts is the number of times the node has restarted after a crash, the node must discard its state when it restarts.
+	// PersistedState tells whether the state that the node has persisted survived the crash, otherwise it starts from scratch.
+	Restarts       int
+	PersistedState bool
 }
 
 func (n *InternalNode) Node() Node {
@@ -57,6 +90,8 @@ func (nodeA *InternalNode) connectNodes(nodeB *InternalNode) {
 		origin: nodeA,
 	}
 	nodeA.peers[nodeB.internalID] = peer
+	nodeA.connectedAt[nodeB.internalID] = nodeA.sim.time
+	nodeA.sim.updateEnergy(nodeA)
 	nodeA.node.OnConnect(peer, nodeB.nodeID)
 
 }
@@ -65,6 +100,8 @@ func (nodeA *InternalNode) disconnectNodes(nodeB *InternalNode) {
 	peer, found := nodeA.getPeer(nodeB)
 	if found {
 		delete(nodeA.peers, nodeB.internalID)
+		delete(nodeA.connectedAt, nodeB.internalID)
+		nodeA.sim.updateEnergy(nodeA)
 		nodeA.node.OnDisconnect(*peer, nodeB.nodeID)
 	} else {
 		panic("could not find node to disconnect in internalNode.disconnectNodes")
@@ -80,31 +117,6 @@ func (nodeA *InternalNode) getPeer(nodeB *InternalNode) (*Peer, bool) {
 	}
 }
 
-func getPeerIndex(peers []InternalPeer, nodeA *InternalNode, nodeB *InternalNode) int {
-	for i, peer := range peers {
-		if peer.a == nodeA {
-			if peer.b == nodeB {
-				return i
-			}
-		} else if peer.a == nodeB {
-			if peer.b == nodeA {
-				return i
-			}
-		}
-	}
-	return -1
-}
-
-func removePeer(peers []InternalPeer, nodeA *InternalNode, nodeB *InternalNode) []InternalPeer {
-	index := getPeerIndex(peers, nodeA, nodeB)
-	if index != -1 {
-		peers[index] = peers[len(peers)-1]
-		return peers[:len(peers)-1]
-	} else {
-		return peers
-	}
-}
-
 func (n *InternalNode) updateData(key string, data interface{}) {
 	n.data[key] = data
 }
@@ -120,7 +132,7 @@ func (n *InternalNode) updateID(nodeID NodeID) {
 }
 
 func (n *InternalNode) delayBy(functionToCall func(), delay time.Duration) {
-	n.sim.pushDelayEvent(n.sim.time+delay, n, functionToCall)
+	n.sim.pushDelayEvent(n.sim.time+n.clock.simulatedDuration(delay), n, functionToCall)
 }
 
 func (n *InternalNode) terminate(err error) {
@@ -133,16 +145,22 @@ func (n *InternalNode) log(message string) {
 	}
 }
 
-func (n *InternalNode) startNode() {
-	nodeArgs := NodeArguments{
+func (n *InternalNode) nodeArguments() NodeArguments {
+	return NodeArguments{
 		Data:      n.Data,
 		UpdateID:  n.updateID,
 		DelayBy:   n.delayBy,
-		Now:       n.sim.Now,
+		Now:       n.now,
 		Terminate: n.terminate,
 		Log:       n.log,
+
+		Restarts:       n.restarts,
+		PersistedState: n.persistState,
 	}
-	n.node.OnStart(nodeArgs)
+}
+
+func (n *InternalNode) startNode() {
+	n.node.OnStart(n.nodeArguments())
 }
 
 func (n *InternalNode) hasNeighbour(target *InternalNode) bool {
@@ -0,0 +1,134 @@
+package loggers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/starling/network_layer"
+	"github.com/starling-protocol/starling/packet_layer"
+)
+
+// EnergyLogger reports the energy used by each node with an energy profile and the time at which its battery ran out,
+// together with the radio energy spent on each type of received packet, when the simulation terminates.
+type EnergyLogger struct {
+	started bool
+	nodes   []*simulator.InternalNode
+	// packetEnergy is the energy used to transmit and receive the received packets of each type
+	packetEnergy map[network_layer.PacketType]float64
+	otherEnergy  float64
+}
+
+func NewEnergyLogger() *EnergyLogger {
+	return &EnergyLogger{
+		started:      false,
+		nodes:        []*simulator.InternalNode{},
+		packetEnergy: make(map[network_layer.PacketType]float64),
+		otherEnergy:  0,
+	}
+}
+
+func (l *EnergyLogger) Init() {
+	l.started = true
+}
+
+func (l *EnergyLogger) NewEvent(e simulator.Event) {
+	// The first event is passed to the loggers before Init, and again when it is processed
+	if !l.started {
+		return
+	}
+
+	switch e.EventType() {
+	case simulator.ADD_NODE:
+		node := e.(*simulator.AddNodeEvent).Node()
+		if _, ok := node.EnergyProfile(); ok {
+			l.nodes = append(l.nodes, node)
+		}
+	case simulator.RCV_MSG:
+		receiveEvent := e.(*simulator.ReceiveEvent)
+		l.addPacket(receiveEvent.Origin(), receiveEvent.Target(), receiveEvent.Packet())
+	case simulator.TERMINATE:
+		l.print(e.Time())
+	}
+}
+
+func (l *EnergyLogger) addPacket(origin *simulator.InternalNode, target *simulator.InternalNode, packet []byte) {
+	energy := 0.0
+	if profile, ok := origin.EnergyProfile(); ok {
+		energy += float64(len(packet)) * profile.TransmitPerByte
+	}
+	if profile, ok := target.EnergyProfile(); ok {
+		energy += float64(len(packet)) * profile.ReceivePerByte
+	}
+	if energy == 0 {
+		return
+	}
+
+	decoder := packet_layer.NewPacketDecoder()
+	decoder.AppendPacket(packet)
+	decodedMsg, err := decoder.ReadMessage()
+	if err != nil {
+		l.otherEnergy += energy
+		return
+	}
+	networkPacket, err := network_layer.DecodeRoutingPacket(decodedMsg)
+	if err != nil {
+		l.otherEnergy += energy
+		return
+	}
+	l.packetEnergy[networkPacket.PacketType()] += energy
+}
+
+func (l *EnergyLogger) print(simulatedTime time.Duration) {
+	fmt.Printf("\n\n----==== ENERGY ====----\n\n")
+
+	total := 0.0
+	depleted := 0
+	firstDepletion := time.Duration(-1)
+	for _, node := range l.nodes {
+		usage, _ := node.EnergyUsage()
+		profile, _ := node.EnergyProfile()
+		total += usage.Total()
+
+		status := ""
+		if depletedAt, ok := node.DepletedAt(); ok {
+			depleted++
+			if firstDepletion < 0 || depletedAt < firstDepletion {
+				firstDepletion = depletedAt
+			}
+			status = fmt.Sprintf("depleted at %s", depletedAt)
+		} else if profile.Capacity > 0 {
+			status = fmt.Sprintf("battery %.2f%%", (1-usage.Total()/profile.Capacity)*100)
+		}
+		fmt.Printf("node: %d, used %.3f J (transmit %.3f, receive %.3f, connected %.3f, scan %.3f) \t%s\n",
+			node.NodeID(), usage.Total(), usage.Transmit, usage.Receive, usage.Connected, usage.Scan, status)
+	}
+	fmt.Printf("\n")
+
+	fmt.Printf("Simulated time: \t\t%s\n", simulatedTime)
+	fmt.Printf("Total energy used: \t\t%.3f J\n", total)
+	fmt.Printf("Nodes depleted: \t\t%d of %d\n", depleted, len(l.nodes))
+	if firstDepletion >= 0 {
+		fmt.Printf("First depletion: \t\t%s\n", firstDepletion)
+	}
+	fmt.Printf("\n")
+
+	radioTotal := l.otherEnergy
+	for _, energy := range l.packetEnergy {
+		radioTotal += energy
+	}
+	fmt.Printf("Radio energy of received packets: \t%.6f J\n", radioTotal)
+	if radioTotal == 0 {
+		return
+	}
+	for _, packetType := range []struct {
+		name       string
+		packetType network_layer.PacketType
+	}{{"RREQ", network_layer.RREQ}, {"RREP", network_layer.RREP}, {"SESS", network_layer.SESS}, {"RERR", network_layer.RERR}} {
+		energy := l.packetEnergy[packetType.packetType]
+		fmt.Printf("%s Packets \t\t\t%.6f J\t(%.2f%%)\n", packetType.name, energy, energy/radioTotal*100)
+	}
+	fmt.Printf("Other Packets \t\t\t%.6f J\t(%.2f%%)\n", l.otherEnergy, l.otherEnergy/radioTotal*100)
+}
+
+func (l *EnergyLogger) Log(str string) {}
@@ -0,0 +1,67 @@
+package loggers
+
+import (
+	"os"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// RecordLogger writes all events to an event log file, which can be replayed using simulator.NewReplaySimulator.
+// The file is closed when the simulation terminates, otherwise Close must be called to complete it.
+type RecordLogger struct {
+	path   string
+	file   *os.File
+	writer *simulator.EventLogWriter
+}
+
+func NewRecordLogger(path string) *RecordLogger {
+	return &RecordLogger{
+		path:   path,
+		file:   nil,
+		writer: nil,
+	}
+}
+
+func (l *RecordLogger) Init() {
+	file, err := os.Create(l.path)
+	check(err)
+	l.file = file
+	l.writer = simulator.NewEventLogWriter(file)
+}
+
+func (l *RecordLogger) NewEvent(e simulator.Event) {
+	// The initial time step is passed to the loggers before Init, it is written when it is processed
+	if l.writer == nil {
+		return
+	}
+
+	check(l.writer.Write(e))
+
+	if e.EventType() == simulator.TERMINATE {
+		check(l.Close())
+	}
+}
+
+// Flush writes the buffered events to the file, it is called when simulator.Run returns
+func (l *RecordLogger) Flush() error {
+	if l.writer == nil {
+		return nil
+	}
+	return l.writer.Flush()
+}
+
+// Close flushes the buffered events and closes the file, no more events are written afterwards
+func (l *RecordLogger) Close() error {
+	if l.writer == nil {
+		return nil
+	}
+	err := l.writer.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.writer = nil
+	return err
+}
+
+func (l *RecordLogger) Log(str string) {
+}
@@ -32,6 +32,8 @@ func (l *StandardLogger) NewEvent(e simulator.Event) {
 			break
 		}
 		fmt.Printf(yellow+"[EVENT] %d %d: [STEP]\n", e.Time().Milliseconds(), e.SequenceNumber())
+	case simulator.MOVEMENT:
+		// Movement events are too frequent to be useful in the log
 	case simulator.CONNECT:
 		connectEvent := e.(*simulator.ConnectEvent)
 		fmt.Printf(cyan+"[EVENT] %d %d: [CONNECT] %d, %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), connectEvent.NodeA().NodeID(), connectEvent.NodeB().NodeID())
@@ -41,6 +43,9 @@ func (l *StandardLogger) NewEvent(e simulator.Event) {
 	case simulator.ADD_NODE:
 		addNodeEvent := e.(*simulator.AddNodeEvent)
 		fmt.Printf(cyan+"[EVENT] %d %d: [ADD_NODE] %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), addNodeEvent.Node().NodeID())
+	case simulator.RM_NODE:
+		removeNodeEvent := e.(*simulator.RemoveNodeEvent)
+		fmt.Printf(cyan+"[EVENT] %d %d: [RM_NODE] %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), removeNodeEvent.Node().NodeID())
 	case simulator.SEND_MSG:
 		sendEvent := e.(*simulator.SendEvent)
 		fmt.Printf(blue+"[EVENT] %d %d: [SEND] %d to %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), sendEvent.Origin().NodeID(), sendEvent.TargetNodeID())
@@ -49,6 +54,20 @@ func (l *StandardLogger) NewEvent(e simulator.Event) {
 		fmt.Printf(blue+"[EVENT] %d %d: [RECEIVE] %d from %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), receiveEvent.Target().NodeID(), receiveEvent.OriginNodeID())
 	case simulator.DELAY:
 		fmt.Printf(blue+"[EVENT] %d %d: [DELAY] \n"+default_color, e.Time().Milliseconds(), e.SequenceNumber())
+	case simulator.PARTITION:
+		fmt.Printf(cyan+"[EVENT] %d %d: [PARTITION] until %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), e.(*simulator.PartitionEvent).HealTime().Milliseconds())
+	case simulator.HEAL:
+		fmt.Printf(cyan+"[EVENT] %d %d: [HEAL]\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber())
+	case simulator.CRASH:
+		crashEvent := e.(*simulator.CrashEvent)
+		fmt.Printf(cyan+"[EVENT] %d %d: [CRASH] %d for %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), crashEvent.Node().NodeID(), crashEvent.Downtime().Milliseconds())
+	case simulator.RESTART:
+		fmt.Printf(cyan+"[EVENT] %d %d: [RESTART] %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), e.(*simulator.RestartEvent).Node().NodeID())
+	case simulator.DEPLETE:
+		fmt.Printf(cyan+"[EVENT] %d %d: [DEPLETE] %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), e.(*simulator.DepleteEvent).Node().NodeID())
+	case simulator.BUFFER_DROP:
+		dropEvent := e.(*simulator.BufferDropEvent)
+		fmt.Printf(blue+"[EVENT] %d %d: [BUFFER_DROP] %d to %d\n"+default_color, e.Time().Milliseconds(), e.SequenceNumber(), dropEvent.Origin().NodeID(), dropEvent.TargetNodeID())
 	case simulator.TERMINATE:
 		terminateEvent := e.(*simulator.TerminateEvent)
 		if terminateEvent.Error() == nil {
@@ -64,5 +83,10 @@ func (l *StandardLogger) NewEvent(e simulator.Event) {
 }
 
 func (l *StandardLogger) Log(str string) {
+	// Nodes restored from a checkpoint log before any event has been processed
+	if l.lastEvent == nil {
+		fmt.Printf("\t[DEBUG] %s\n", str)
+		return
+	}
 	fmt.Printf("\t[DEBUG] %d %d: %s\n", l.lastEvent.Time().Milliseconds(), l.lastEvent.SequenceNumber(), str)
 }
@@ -10,7 +10,12 @@ import (
 )
 
 type StatisticsLogger struct {
+	// Silent disables printing the statistics when the simulation terminates
+	Silent bool
+
 	realStartTime time.Time
+	realTime      time.Duration
+	simulatedTime time.Duration
 
 	packagesSent     int64
 	packagesReceived int64
@@ -23,6 +28,8 @@ type StatisticsLogger struct {
 	routeReplies         int64
 	routeErrors          int64
 	routeSessionData     int64
+
+	bufferDrops int64
 }
 
 func NewStatisticsLogger() *StatisticsLogger {
@@ -37,6 +44,7 @@ func NewStatisticsLogger() *StatisticsLogger {
 		routeReplies:         0,
 		routeErrors:          0,
 		routeSessionData:     0,
+		bufferDrops:          0,
 	}
 }
 
@@ -80,10 +88,18 @@ func (l *StatisticsLogger) NewEvent(e simulator.Event) {
 	case simulator.SEND_MSG:
 		// receiveEvent := e.(*simulator.ReceiveEvent)
 		l.packagesSent++
+	case simulator.BUFFER_DROP:
+		l.bufferDrops++
 	case simulator.TERMINATE:
 
 		realtime := time.Since(l.realStartTime)
 		terminateEvent := e.(*simulator.TerminateEvent)
+		l.realTime = realtime
+		l.simulatedTime = terminateEvent.Time()
+
+		if l.Silent {
+			return
+		}
 
 		fmt.Printf("\n\n----==== STATISTICS ====----\n\n")
 
@@ -94,6 +110,7 @@ func (l *StatisticsLogger) NewEvent(e simulator.Event) {
 		fmt.Printf("Packages Sent: \t\t\t%d\n", l.packagesSent)
 		fmt.Printf("Packages Received: \t\t%d\n", l.packagesReceived)
 		fmt.Printf("Dropped Packages: \t\t%d\n", l.packagesSent-l.packagesReceived) // Caveat: This also counts messages that were sent, but did not get received before sim was stopped
+		fmt.Printf("Buffer Drops: \t\t\t%d\n", l.bufferDrops)
 		fmt.Printf("\n")
 
 		kbReceived := float64(l.bytesReceived) / 1000
@@ -117,3 +134,22 @@ func (l *StatisticsLogger) NewEvent(e simulator.Event) {
 }
 
 func (l *StatisticsLogger) Log(str string) {}
+
+// Metrics returns the statistics collected so far, keyed by metric name
+func (l *StatisticsLogger) Metrics() map[string]float64 {
+	return map[string]float64{
+		"simulated_time_s":  l.simulatedTime.Seconds(),
+		"real_time_s":       l.realTime.Seconds(),
+		"packages_sent":     float64(l.packagesSent),
+		"packages_received": float64(l.packagesReceived),
+		"bytes_received":    float64(l.bytesReceived),
+		"metadata_packages": float64(l.metaDataPackets),
+		"metadata_bytes":    float64(l.metadataBytes),
+		"network_packages":  float64(l.networkLevelPackages),
+		"rreq_packets":      float64(l.routeRequests),
+		"rrep_packets":      float64(l.routeReplies),
+		"sess_packets":      float64(l.routeSessionData),
+		"rerr_packets":      float64(l.routeErrors),
+		"buffer_drops":      float64(l.bufferDrops),
+	}
+}
@@ -11,6 +11,20 @@ import (
 type SyncLogger struct {
 	realStartTime      time.Time
 	syncStateScenarios []*starling_node.ScenarioStoreSyncState
+	partitions         []*partitionSync
+}
+
+// partitionSync records how the sync states converge after a partition heals
+type partitionSync struct {
+	start           time.Duration
+	heal            time.Duration
+	healed          bool
+	closed          bool
+	receivedAtStart int
+	receivedAtHeal  int
+	receivedAfter   int
+	// lastUpdate is the time of the last change to a sync state before the next partition, or the end of the simulation
+	lastUpdate time.Duration
 }
 
 func NewSyncLogger() *SyncLogger {
@@ -28,7 +42,25 @@ func (l *SyncLogger) Init() {
 func (l *SyncLogger) NewEvent(e simulator.Event) {
 
 	switch e.EventType() {
+	case simulator.PARTITION:
+		l.closePartitions()
+		partitionEvent := e.(*simulator.PartitionEvent)
+		l.partitions = append(l.partitions, &partitionSync{
+			start:           partitionEvent.Time(),
+			heal:            partitionEvent.HealTime(),
+			receivedAtStart: l.totalReceived(),
+		})
+	case simulator.HEAL:
+		healEvent := e.(*simulator.HealEvent)
+		for _, p := range l.partitions {
+			if !p.healed && p.start == healEvent.StartTime() && p.heal == healEvent.Time() {
+				p.healed = true
+				p.receivedAtHeal = l.totalReceived()
+				break
+			}
+		}
 	case simulator.TERMINATE:
+		l.closePartitions()
 
 		nodeLines := []string{}
 		totalMessageSentGoal := 0
@@ -79,9 +111,53 @@ func (l *SyncLogger) NewEvent(e simulator.Event) {
 		}
 		fmt.Printf("Received to goal ratio: \t%2f%%\n", ratio*100)
 
+		for i, p := range l.partitions {
+			fmt.Printf("\nPartition %d from %s to %s:\n", i, p.start, p.heal)
+			if !p.healed {
+				fmt.Printf("Messages received: \t%d at start, not healed\n", p.receivedAtStart)
+				continue
+			}
+			fmt.Printf("Messages received: \t%d at start, %d at heal, %d after\n", p.receivedAtStart, p.receivedAtHeal, p.receivedAfter)
+			if p.lastUpdate > p.heal {
+				fmt.Printf("Converged after: \t%s\n", p.lastUpdate-p.heal)
+			} else {
+				fmt.Printf("Converged after: \tno changes after healing\n")
+			}
+		}
+
 	default:
 		return
 	}
 }
 
 func (l *SyncLogger) Log(str string) {}
+
+func (l *SyncLogger) totalReceived() int {
+	total := 0
+	for _, scenario := range l.syncStateScenarios {
+		if state := scenario.GetSyncState(); state != nil {
+			for _, nodeState := range state.NodeStates {
+				total += len(nodeState)
+			}
+		}
+	}
+	return total
+}
+
+// closePartitions records the state after the partitions that have healed, before the next partition or the end of the simulation
+func (l *SyncLogger) closePartitions() {
+	var lastUpdate time.Duration
+	for _, scenario := range l.syncStateScenarios {
+		if updatedAt := scenario.UpdatedAt(); !updatedAt.IsZero() {
+			lastUpdate = max(lastUpdate, updatedAt.Sub(time.Unix(0, 0)))
+		}
+	}
+	received := l.totalReceived()
+	for _, p := range l.partitions {
+		if p.healed && !p.closed {
+			p.closed = true
+			p.receivedAfter = received
+			p.lastUpdate = lastUpdate
+		}
+	}
+}
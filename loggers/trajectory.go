@@ -0,0 +1,125 @@
+package loggers
+
+import (
+	"encoding/csv"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// TrajectoryLogger writes the positions of all nodes to a CSV file at a fixed interval of simulated time.
+// If a projection is given, the positions are written as WGS84 latitude, longitude and altitude.
+// The file is closed when the simulation terminates, otherwise Close must be called to complete it.
+type TrajectoryLogger struct {
+	path       string
+	interval   time.Duration
+	projection simulator.Projection
+	file       *os.File
+	writer     *csv.Writer
+	nodes      []*simulator.InternalNode
+	nextSample time.Duration
+}
+
+func NewTrajectoryLogger(path string, interval time.Duration, projection simulator.Projection) *TrajectoryLogger {
+	if interval <= 0 {
+		panic("trajectory logger interval must be positive")
+	}
+	return &TrajectoryLogger{
+		path:       path,
+		interval:   interval,
+		projection: projection,
+		file:       nil,
+		writer:     nil,
+		nodes:      []*simulator.InternalNode{},
+		nextSample: 0,
+	}
+}
+
+func (l *TrajectoryLogger) Init() {
+	file, err := os.Create(l.path)
+	check(err)
+	l.file = file
+	l.writer = csv.NewWriter(file)
+
+	if l.projection != nil {
+		check(l.writer.Write([]string{"time", "node", "latitude", "longitude", "altitude", "floor"}))
+	} else {
+		check(l.writer.Write([]string{"time", "node", "x", "y", "z", "floor"}))
+	}
+}
+
+func (l *TrajectoryLogger) NewEvent(e simulator.Event) {
+	// The initial time step is passed to the loggers before Init, it is sampled when the next event is processed
+	if l.writer == nil {
+		return
+	}
+
+	// Samples are written once all events before them are processed, such that nodes added at the time are included
+	for l.nextSample < e.Time() || (e.EventType() == simulator.TERMINATE && l.nextSample == e.Time()) {
+		l.sample(l.nextSample)
+		l.nextSample += l.interval
+	}
+
+	switch e.EventType() {
+	case simulator.ADD_NODE:
+		l.nodes = append(l.nodes, e.(*simulator.AddNodeEvent).Node())
+	case simulator.RM_NODE:
+		l.nodes = slices.DeleteFunc(l.nodes, func(n *simulator.InternalNode) bool {
+			return n == e.(*simulator.RemoveNodeEvent).Node()
+		})
+	case simulator.DEPLETE:
+		l.nodes = slices.DeleteFunc(l.nodes, func(n *simulator.InternalNode) bool {
+			return n == e.(*simulator.DepleteEvent).Node()
+		})
+	case simulator.TERMINATE:
+		check(l.Close())
+	}
+}
+
+// Flush writes the buffered samples to the file, it is called when simulator.Run returns
+func (l *TrajectoryLogger) Flush() error {
+	if l.writer == nil {
+		return nil
+	}
+	l.writer.Flush()
+	return l.writer.Error()
+}
+
+// Close flushes the buffered samples and closes the file, no more samples are written afterwards
+func (l *TrajectoryLogger) Close() error {
+	if l.writer == nil {
+		return nil
+	}
+	err := l.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.writer = nil
+	return err
+}
+
+func (l *TrajectoryLogger) sample(t time.Duration) {
+	seconds := strconv.FormatFloat(t.Seconds(), 'f', 3, 64)
+	for _, node := range l.nodes {
+		coords := node.PositionAt(t)
+		a, b, c := coords.X, coords.Y, coords.Z
+		if l.projection != nil {
+			g := l.projection.Unproject(coords)
+			a, b, c = g.Latitude, g.Longitude, g.Altitude
+		}
+		check(l.writer.Write([]string{
+			seconds,
+			strconv.FormatInt(int64(node.NodeID()), 10),
+			strconv.FormatFloat(a, 'f', -1, 64),
+			strconv.FormatFloat(b, 'f', -1, 64),
+			strconv.FormatFloat(c, 'f', -1, 64),
+			strconv.Itoa(coords.Floor),
+		}))
+	}
+}
+
+func (l *TrajectoryLogger) Log(str string) {
+}
@@ -1,6 +1,8 @@
 package movement_profiles
 
 import (
+	"bytes"
+	"encoding/gob"
 	"time"
 
 	"github.com/starling-protocol/simulator"
@@ -35,3 +37,15 @@ func (m *LinearNode) RegisterMovements(coords simulator.Coordinate) simulator.Mo
 		return simulator.MovementInstruction{m.StartCoords, m.Seconds}
 	}
 }
+
+// Snapshot implements simulator.Snapshotter.
+func (m *LinearNode) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(m.reachedStart)
+	return buf.Bytes(), err
+}
+
+// RestoreSnapshot implements simulator.Snapshotter.
+func (m *LinearNode) RestoreSnapshot(snapshot []byte) error {
+	return gob.NewDecoder(bytes.NewReader(snapshot)).Decode(&m.reachedStart)
+}
@@ -1,6 +1,8 @@
 package movement_profiles
 
 import (
+	"bytes"
+	"encoding/gob"
 	"math/rand"
 	"time"
 
@@ -69,3 +71,15 @@ func (m *RandomWaypointNode) RegisterMovements(coords simulator.Coordinate) simu
 		}
 	}
 }
+
+// Snapshot implements simulator.Snapshotter.
+func (m *RandomWaypointNode) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(m.isPaused)
+	return buf.Bytes(), err
+}
+
+// RestoreSnapshot implements simulator.Snapshotter.
+func (m *RandomWaypointNode) RestoreSnapshot(snapshot []byte) error {
+	return gob.NewDecoder(bytes.NewReader(snapshot)).Decode(&m.isPaused)
+}
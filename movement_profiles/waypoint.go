@@ -1,6 +1,9 @@
 package movement_profiles
 
 import (
+	"bytes"
+	"encoding/gob"
+
 	"github.com/starling-protocol/simulator"
 )
 
@@ -32,3 +35,16 @@ func (w *WaypointNode) RegisterMovements(coords simulator.Coordinate) simulator.
 	w.points = w.points[1:]
 	return point
 }
+
+// Snapshot implements simulator.Snapshotter.
+func (w *WaypointNode) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(w.points)
+	return buf.Bytes(), err
+}
+
+// RestoreSnapshot implements simulator.Snapshotter.
+func (w *WaypointNode) RestoreSnapshot(snapshot []byte) error {
+	w.points = []simulator.MovementInstruction{}
+	return gob.NewDecoder(bytes.NewReader(snapshot)).Decode(&w.points)
+}
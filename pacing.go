@@ -0,0 +1,143 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxPacingSleep is the longest a paced run sleeps at a time, so it notices cancellation and timeouts in time
+const maxPacingSleep = 50 * time.Millisecond
+
+// DefaultStallThreshold is the lag behind the wall clock after which a Pacer reports a stall
+const DefaultStallThreshold = 100 * time.Millisecond
+
+// A Pacer advances the simulated time in lockstep with the wall clock, multiplied by a speed factor.
+// When processing the events takes longer than the stall threshold, the simulator has fallen behind the wall clock
+// and the stall is reported. Simulated time keeps advancing when there are no events to process.
+type Pacer struct {
+	s              *Simulator
+	speed          float64
+	stallThreshold time.Duration
+	onStall        func(s *Simulator, lag time.Duration)
+
+	// time is the simulated time that the wall clock has reached
+	time       time.Duration
+	lastUpdate time.Time
+	stalled    bool
+	stalls     int
+	maxLag     time.Duration
+}
+
+// NewPacer creates a pacer which advances the simulated time speed times as fast as the wall clock,
+// starting from the current simulated time. A speed of 0 pauses the simulation.
+func (s *Simulator) NewPacer(speed float64) *Pacer {
+	if speed < 0 {
+		panic("pacing speed must not be negative")
+	}
+	return &Pacer{
+		s:              s,
+		speed:          speed,
+		stallThreshold: DefaultStallThreshold,
+		onStall:        nil,
+		time:           s.time,
+	}
+}
+
+// Speed returns the speed factor of the pacer
+func (p *Pacer) Speed() float64 {
+	return p.speed
+}
+
+// SetSpeed changes the speed factor from now on, a speed of 0 pauses the simulation
+func (p *Pacer) SetSpeed(speed float64) {
+	if speed < 0 {
+		panic("pacing speed must not be negative")
+	}
+	p.advanceClock()
+	p.speed = speed
+}
+
+// SetStallThreshold sets the lag behind the wall clock after which a stall is reported
+func (p *Pacer) SetStallThreshold(threshold time.Duration) {
+	p.stallThreshold = threshold
+}
+
+// OnStall sets a function which is called with the lag after each update that leaves the simulator behind the wall clock
+func (p *Pacer) OnStall(onStall func(s *Simulator, lag time.Duration)) {
+	p.onStall = onStall
+}
+
+// Time returns the simulated time that the wall clock has reached
+func (p *Pacer) Time() time.Duration {
+	return p.time
+}
+
+// Stalls returns the number of times the simulator has fallen behind the wall clock
+func (p *Pacer) Stalls() int {
+	return p.stalls
+}
+
+// MaxLag returns the largest lag behind the wall clock that has been reported as a stall
+func (p *Pacer) MaxLag() time.Duration {
+	return p.maxLag
+}
+
+// Update processes the events until the simulated time that corresponds to the current wall-clock time
+func (p *Pacer) Update() error {
+	_, err := p.update(math.MaxInt64, nil)
+	return err
+}
+
+// advanceClock advances the simulated time of the pacer by the wall-clock time since it was last advanced
+func (p *Pacer) advanceClock() time.Time {
+	now := time.Now()
+	if !p.lastUpdate.IsZero() {
+		p.time += time.Duration(float64(now.Sub(p.lastUpdate)) * p.speed)
+	}
+	p.lastUpdate = now
+	return now
+}
+
+// update processes the events until the simulated time of the pacer, but not beyond until
+func (p *Pacer) update(until time.Duration, stop func() bool) (bool, error) {
+	start := p.advanceClock()
+	terminated, err := p.s.processEvents(min(p.time, until), stop)
+
+	// The events were due at the start of the update, so the time spent processing them is the lag behind the wall clock
+	lag := time.Since(start)
+	if p.speed > 0 && p.stallThreshold > 0 && lag > p.stallThreshold {
+		if !p.stalled {
+			p.stalls++
+			p.s.logDebug(fmt.Sprintf("simulator:stall:%s:%s", p.s.time, lag))
+		}
+		p.stalled = true
+		p.maxLag = max(p.maxLag, lag)
+		if p.onStall != nil {
+			p.onStall(p.s, lag)
+		}
+	} else {
+		p.stalled = false
+	}
+	return terminated, err
+}
+
+// wait sleeps until the next event is due, or until the context is done
+func (p *Pacer) wait(ctx context.Context, until time.Duration) {
+	sleep := maxPacingSleep
+	if p.speed > 0 {
+		next := until
+		if !p.s.replaying && p.s.eventQueue.Len() > 0 {
+			next = min(next, (*p.s.eventQueue)[0].Time())
+		}
+		sleep = time.Duration(math.Max(0, math.Min(float64(sleep), float64(next-p.time)/p.speed)))
+	}
+
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
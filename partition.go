@@ -0,0 +1,171 @@
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// A Cut is a line segment that links cannot cross while its partition is active. It extends through all floors.
+type Cut struct {
+	From Coordinate
+	To   Coordinate
+}
+
+// A Partition splits the network while it is active. The links crossing it are disconnected,
+// and the nodes on either side cannot connect or exchange packets until it heals, while they keep moving as normal.
+type Partition struct {
+	// Groups contains the IDs of the nodes in each group, nodes in different groups are separated.
+	// The nodes that are not in any group form a group of their own.
+	Groups [][]NodeID
+	// Cuts separate the nodes on either side of them. They are not supported with ContactPrediction.
+	Cuts []Cut
+}
+
+// scheduledPartition is a partition together with the times it starts and heals
+type scheduledPartition struct {
+	partition Partition
+	index     int
+	start     time.Duration
+	heal      time.Duration
+	groups    map[NodeID]int
+}
+
+// partitionGroups maps the IDs of the nodes in the groups of the partition to the index of their group
+func partitionGroups(partition Partition) (map[NodeID]int, error) {
+	groups := make(map[NodeID]int)
+	for i, group := range partition.Groups {
+		for _, id := range group {
+			if _, found := groups[id]; found {
+				return nil, fmt.Errorf("node %d is in more than one partition group", id)
+			}
+			groups[id] = i
+		}
+	}
+	return groups, nil
+}
+
+func (p *scheduledPartition) separates(nodeA *InternalNode, nodeB *InternalNode) bool {
+	if len(p.groups) > 0 {
+		groupA, foundA := p.groups[nodeA.nodeID]
+		groupB, foundB := p.groups[nodeB.nodeID]
+		if foundA != foundB || groupA != groupB {
+			return true
+		}
+	}
+	for _, cut := range p.partition.Cuts {
+		if segmentsIntersect(nodeA.coords, nodeB.coords, cut.From, cut.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchedulePartition splits the network from start until heal, which must be later than start.
+// Partitions must be scheduled in the same order before a checkpoint is restored.
+func (s *Simulator) SchedulePartition(start time.Duration, heal time.Duration, partition Partition) {
+	if start < s.time {
+		panic("partition cannot start in the past")
+	}
+	if heal <= start {
+		panic("partition must heal after it starts")
+	}
+	if len(partition.Cuts) > 0 && s.options.ContactPrediction {
+		panic("partition cuts are not supported with contact prediction")
+	}
+
+	groups, err := partitionGroups(partition)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	p := &scheduledPartition{
+		partition: partition,
+		index:     len(s.partitions),
+		start:     start,
+		heal:      heal,
+		groups:    groups,
+	}
+	s.partitions = append(s.partitions, p)
+	s.pushPartitionEvent(start, p)
+	s.pushHealEvent(heal, p)
+}
+
+// Partitions returns the partitions that are currently active
+func (s *Simulator) Partitions() []Partition {
+	partitions := []Partition{}
+	for _, p := range s.regionMap.partitions {
+		partitions = append(partitions, p.partition)
+	}
+	return partitions
+}
+
+func (s *Simulator) startPartition(p *scheduledPartition) {
+	s.logDebug(fmt.Sprintf("simulator:partition:start:%d", p.index))
+	s.regionMap.partitions = append(s.regionMap.partitions, p)
+	s.updatePartitionedContacts(p)
+}
+
+func (s *Simulator) healPartition(p *scheduledPartition) {
+	s.logDebug(fmt.Sprintf("simulator:partition:heal:%d", p.index))
+	s.regionMap.partitions = slices.DeleteFunc(s.regionMap.partitions, func(other *scheduledPartition) bool {
+		return other == p
+	})
+	s.updatePartitionedContacts(p)
+}
+
+// updatePartitionedContacts updates the connections between the nodes separated by the partition when it starts or heals.
+// When ContactPrediction is disabled, the connections are updated at the next time step.
+func (s *Simulator) updatePartitionedContacts(p *scheduledPartition) {
+	if !s.options.ContactPrediction {
+		for _, node := range s.nodes {
+			node.dirty = true
+		}
+		return
+	}
+
+	for i, nodeA := range s.nodes {
+		for _, nodeB := range s.nodes[i+1:] {
+			if p.separates(nodeA, nodeB) {
+				s.predictContact(nodeA, nodeB)
+			}
+		}
+	}
+}
+
+// separated reports whether an active partition separates the nodes
+func (r *RegionMap) separated(nodeA *InternalNode, nodeB *InternalNode) bool {
+	for _, p := range r.partitions {
+		if p.separates(nodeA, nodeB) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Simulator) pushPartitionEvent(time time.Duration, p *scheduledPartition) {
+	event := &PartitionEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		partition: p,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+}
+
+func (s *Simulator) pushHealEvent(time time.Duration, p *scheduledPartition) {
+	event := &HealEvent{
+		BaseEvent: BaseEvent{
+			time:           time,
+			sequenceNumber: s.currentSequenceNumber,
+			parentEvent:    s.lastEvent,
+		},
+		partition: p,
+	}
+	heap.Push(s.eventQueue, event)
+	s.currentSequenceNumber++
+}
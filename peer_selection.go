@@ -0,0 +1,244 @@
+package simulator
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// PeerInfo describes a peer of a node, or a candidate peer, to a PeerSelection
+type PeerInfo struct {
+	NodeID   NodeID
+	Distance float64
+	// ConnectedAt is the time at which the peer was connected, it is the current time for candidates
+	ConnectedAt time.Duration
+}
+
+// A PeerSelection decides which nodes a node connects to, when it has no free connection slots.
+// Nodes that are declined or evicted are not connected again until they have left range,
+// or until both have a free connection slot because other connections have ended.
+type PeerSelection interface {
+	// Evict is called when a node without free connection slots comes within range of candidate.
+	// peers are the current peers of the node, ordered by the time they were connected.
+	// It returns the index of the peer to disconnect to make room for the candidate, or -1 to not connect to the candidate.
+	Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int
+}
+
+// KeepPeers keeps the existing connections, and declines new candidates
+type KeepPeers struct{}
+
+func (KeepPeers) Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int {
+	return -1
+}
+
+// NearestPeers keeps the nearest nodes, the farthest peer is evicted for a candidate which is nearer than it
+type NearestPeers struct{}
+
+func (NearestPeers) Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int {
+	farthest := -1
+	farthestDistance := candidate.Distance
+	for i, peer := range peers {
+		if peer.Distance > farthestDistance {
+			farthest = i
+			farthestDistance = peer.Distance
+		}
+	}
+	return farthest
+}
+
+// RandomPeers drops a uniformly random node among the peers and the candidate
+type RandomPeers struct{}
+
+func (RandomPeers) Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int {
+	i := random.Intn(len(peers) + 1)
+	if i == len(peers) {
+		return -1
+	}
+	return i
+}
+
+// OldestPeers evicts the peer which has been connected the longest, such that new candidates are always connected
+type OldestPeers struct{}
+
+func (OldestPeers) Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int {
+	if len(peers) == 0 {
+		return -1
+	}
+	return 0
+}
+
+// A PeerSelector is a Node which chooses its own peers, it is consulted by ProtocolPeers
+type PeerSelector interface {
+	// SelectPeer returns the peer to evict for the candidate, which must be one of peers, or accept = false to not connect to the candidate
+	SelectPeer(peers []PeerInfo, candidate PeerInfo) (evict NodeID, accept bool)
+}
+
+// ProtocolPeers lets nodes implementing PeerSelector choose their peers, other nodes keep their existing connections
+type ProtocolPeers struct{}
+
+func (ProtocolPeers) Evict(node Node, peers []PeerInfo, candidate PeerInfo, random *rand.Rand) int {
+	selector, ok := node.(PeerSelector)
+	if !ok {
+		return -1
+	}
+	evict, accept := selector.SelectPeer(peers, candidate)
+	if !accept {
+		return -1
+	}
+	i := slices.IndexFunc(peers, func(peer PeerInfo) bool {
+		return peer.NodeID == evict
+	})
+	if i < 0 {
+		panic(fmt.Sprintf("node %d selected node %d to evict, which is not one of its peers", node.ID(), evict))
+	}
+	return i
+}
+
+// hasFreeSlot returns whether the node can connect to another node without evicting one of its peers
+func (n *InternalNode) hasFreeSlot() bool {
+	return n.maxConnections <= 0 || len(n.peers) < n.maxConnections
+}
+
+// selectPeer returns whether the node has room for the candidate, and the peer to evict to make room for it, if any
+func (n *InternalNode) selectPeer(candidate *InternalNode) (evict *InternalNode, accept bool) {
+	if n.hasFreeSlot() {
+		return nil, true
+	}
+
+	peers := make([]*InternalNode, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer.target)
+	}
+	slices.SortFunc(peers, func(a, b *InternalNode) int {
+		return cmp.Or(cmp.Compare(n.connectedAt[a.internalID], n.connectedAt[b.internalID]), cmp.Compare(a.internalID, b.internalID))
+	})
+
+	infos := make([]PeerInfo, len(peers))
+	for i, peer := range peers {
+		infos[i] = PeerInfo{peer.nodeID, math.Sqrt(nodeDistSquared(n, peer)), n.connectedAt[peer.internalID]}
+	}
+
+	i := n.peerSelection.Evict(n.node, infos, PeerInfo{candidate.nodeID, math.Sqrt(nodeDistSquared(n, candidate)), n.sim.time}, n.random)
+	if i < 0 {
+		return nil, false
+	}
+	return peers[i], true
+}
+
+// admitConnection is called before the nodes are connected by the connect event.
+// It evicts peers to make room for the connection, or declines it if one of the nodes will not connect.
+func (s *Simulator) admitConnection(connectEvent *ConnectEvent) bool {
+	nodeA := connectEvent.nodeA
+	nodeB := connectEvent.nodeB
+	if nodeA.maxConnections <= 0 && nodeB.maxConnections <= 0 {
+		return true
+	}
+	if s.options.ContactPrediction {
+		s.refreshPosition(nodeA)
+		s.refreshPosition(nodeB)
+	}
+
+	evictA, acceptA := nodeA.selectPeer(nodeB)
+	evictB, acceptB := nodeB.selectPeer(nodeA)
+	if !acceptA || !acceptB {
+		s.logDebug(fmt.Sprintf("simulator:connection:declined:%d:%d", nodeA.nodeID, nodeB.nodeID))
+		s.completeDiscovery(nodeA, nodeB)
+		if !s.options.ContactPrediction && !s.tracksConnectionAttempts() {
+			s.peers.remove(nodeA, nodeB)
+		}
+		s.decline(nodeA, nodeB)
+		return false
+	}
+
+	if evictA != nil {
+		s.evict(nodeA, evictA)
+	}
+	if evictB != nil {
+		s.evict(nodeB, evictB)
+	}
+	return true
+}
+
+// evict disconnects the peer from the node, to make room for another connection
+func (s *Simulator) evict(node *InternalNode, peer *InternalNode) {
+	s.logDebug(fmt.Sprintf("simulator:connection:evict:%d:%d", node.nodeID, peer.nodeID))
+
+	disconnectEvent := s.NewDisconnectEvent(s.time, node, peer)
+	s.currentSequenceNumber++
+	s.updateLoggers(disconnectEvent)
+
+	// The nodes may already be about to disconnect because they are leaving range
+	s.cancelDeparture(node, peer)
+	s.cancelDrop(node, peer)
+	s.peers.remove(node, peer)
+	s.disconnectNodes(node, peer)
+	s.reconsiderDeclined(peer)
+	s.decline(node, peer)
+}
+
+// decline keeps the nodes from connecting until they have left range
+func (s *Simulator) decline(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	s.declined[pair] = [2]*InternalNode{nodeA, nodeB}
+
+	// The predicted disconnect no longer applies
+	for _, e := range s.predictions[pair] {
+		e.base().cancelled = true
+	}
+	delete(s.predictions, pair)
+}
+
+// reconsiderDeclined lets the node connect again to the nodes it was declined or evicted by, or has declined or evicted,
+// once both have a free connection slot. Pairs where one of the nodes is still full stay declined,
+// such that connecting them again never evicts another peer.
+func (s *Simulator) reconsiderDeclined(node *InternalNode) {
+	if !node.hasFreeSlot() {
+		return
+	}
+
+	pairs := []nodePair{}
+	for pair, nodes := range s.declined {
+		if (nodes[0] == node || nodes[1] == node) && nodes[0].hasFreeSlot() && nodes[1].hasFreeSlot() {
+			pairs = append(pairs, pair)
+		}
+	}
+	slices.SortFunc(pairs, func(a, b nodePair) int {
+		return cmp.Or(cmp.Compare(a.a, b.a), cmp.Compare(a.b, b.b))
+	})
+
+	for _, pair := range pairs {
+		nodes := s.declined[pair]
+		delete(s.declined, pair)
+		if s.options.ContactPrediction {
+			s.predictContact(nodes[0], nodes[1])
+		} else {
+			nodes[0].dirty = true
+			nodes[1].dirty = true
+		}
+	}
+}
+
+func (s *Simulator) isDeclined(nodeA *InternalNode, nodeB *InternalNode) bool {
+	_, found := s.declined[newNodePair(nodeA, nodeB)]
+	return found
+}
+
+// forgetDeclinedOutOfRange is used when ContactPrediction is disabled, to allow declined nodes that have left range to connect again
+func (s *Simulator) forgetDeclinedOutOfRange() {
+	for pair, nodes := range s.declined {
+		if !s.regionMap.withinContactRange(nodes[0], nodes[1]) {
+			delete(s.declined, pair)
+		}
+	}
+}
+
+func (s *Simulator) forgetDeclined(node *InternalNode) {
+	for pair, nodes := range s.declined {
+		if nodes[0] == node || nodes[1] == node {
+			delete(s.declined, pair)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package simulator
+
+// A peerSet contains the connected pairs of nodes, indexed by their node pair
+type peerSet struct {
+	peers []InternalPeer
+	index map[nodePair]int
+}
+
+func newPeerSet() peerSet {
+	return peerSet{
+		peers: []InternalPeer{},
+		index: make(map[nodePair]int),
+	}
+}
+
+// add adds the pair of nodes, unless they are already in the set
+func (p *peerSet) add(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	if _, found := p.index[pair]; found {
+		return
+	}
+	p.index[pair] = len(p.peers)
+	p.peers = append(p.peers, InternalPeer{nodeA, nodeB, make(map[string]interface{}), make(map[string]interface{})})
+}
+
+// get returns the pair of nodes, or nil if they are not in the set
+func (p *peerSet) get(nodeA *InternalNode, nodeB *InternalNode) *InternalPeer {
+	i, found := p.index[newNodePair(nodeA, nodeB)]
+	if !found {
+		return nil
+	}
+	return &p.peers[i]
+}
+
+// remove removes the pair of nodes by moving the last pair into its place
+func (p *peerSet) remove(nodeA *InternalNode, nodeB *InternalNode) {
+	pair := newNodePair(nodeA, nodeB)
+	i, found := p.index[pair]
+	if !found {
+		return
+	}
+	delete(p.index, pair)
+
+	last := len(p.peers) - 1
+	if i != last {
+		p.peers[i] = p.peers[last]
+		p.index[newNodePair(p.peers[i].a, p.peers[i].b)] = i
+	}
+	p.peers = p.peers[:last]
+}
+
+// removeNode removes all pairs containing the node, keeping the order of the remaining pairs
+func (p *peerSet) removeNode(node *InternalNode) {
+	kept := p.peers[:0]
+	for _, peer := range p.peers {
+		if peer.a != node && peer.b != node {
+			kept = append(kept, peer)
+		}
+	}
+	p.peers = kept
+	p.reindex()
+}
+
+// restore replaces the pairs of the set
+func (p *peerSet) restore(peers []InternalPeer) {
+	p.peers = peers
+	p.reindex()
+}
+
+func (p *peerSet) reindex() {
+	p.index = make(map[nodePair]int, len(p.peers))
+	for i, peer := range p.peers {
+		p.index[newNodePair(peer.a, peer.b)] = i
+	}
+}
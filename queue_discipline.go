@@ -0,0 +1,88 @@
+package simulator
+
+import (
+	"math/rand"
+)
+
+// A QueueDiscipline decides which packets are kept when a node sends a packet, and its send buffer is congested.
+// The buffer of a node holds the packets waiting to be transmitted, as well as the packets in transmission.
+// The packet at the head of the buffer is scheduled for transmission and cannot be dropped, so it is not offered to the queue discipline.
+type QueueDiscipline interface {
+	// Admit is called whenever the node sends a packet.
+	// queued contains the packets waiting behind the head of the buffer, oldest first, and occupancy is the number of packets in the buffer,
+	// including those in transmission.
+	// It returns whether the packet is accepted, and the index of a queued packet to drop to make room for it, or -1.
+	Admit(queued [][]byte, packet []byte, occupancy int, capacity int, random *rand.Rand) (accept bool, drop int)
+}
+
+// DropTail drops new packets while the buffer is full
+type DropTail struct{}
+
+func (DropTail) Admit(queued [][]byte, packet []byte, occupancy int, capacity int, random *rand.Rand) (bool, int) {
+	return occupancy < capacity, -1
+}
+
+// DropHead drops the oldest packet waiting to be transmitted to make room for new packets, never the packet at the head of the buffer
+type DropHead struct{}
+
+func (DropHead) Admit(queued [][]byte, packet []byte, occupancy int, capacity int, random *rand.Rand) (bool, int) {
+	if occupancy < capacity {
+		return true, -1
+	}
+	if len(queued) == 0 {
+		return false, -1
+	}
+	return true, 0
+}
+
+// RandomEarlyDrop drops new packets with a probability which increases linearly from 0 to MaxProbability,
+// as the fraction of the buffer in use grows from MinThreshold to MaxThreshold. Above MaxThreshold all new packets are dropped.
+type RandomEarlyDrop struct {
+	MinThreshold   float64
+	MaxThreshold   float64
+	MaxProbability float64
+}
+
+func (d RandomEarlyDrop) Admit(queued [][]byte, packet []byte, occupancy int, capacity int, random *rand.Rand) (bool, int) {
+	if occupancy >= capacity {
+		return false, -1
+	}
+
+	fill := float64(occupancy) / float64(capacity)
+	if fill < d.MinThreshold {
+		return true, -1
+	}
+	if fill >= d.MaxThreshold {
+		return false, -1
+	}
+
+	probability := d.MaxProbability * (fill - d.MinThreshold) / (d.MaxThreshold - d.MinThreshold)
+	return random.Float64() >= probability, -1
+}
+
+// PriorityDrop makes room for new packets by dropping the newest queued packet of the lowest class,
+// if it is lower than the class of the new packet. Higher classes are more important.
+type PriorityDrop struct {
+	Classify func(packet []byte) int
+}
+
+func (d PriorityDrop) Admit(queued [][]byte, packet []byte, occupancy int, capacity int, random *rand.Rand) (bool, int) {
+	if occupancy < capacity {
+		return true, -1
+	}
+
+	packetClass := d.Classify(packet)
+	lowest := -1
+	lowestClass := packetClass
+	for i, queuedPacket := range queued {
+		if class := d.Classify(queuedPacket); class <= lowestClass {
+			lowest = i
+			lowestClass = class
+		}
+	}
+
+	if lowest < 0 || lowestClass >= packetClass {
+		return false, -1
+	}
+	return true, lowest
+}
@@ -0,0 +1,86 @@
+package simulator
+
+import (
+	"math"
+	"time"
+)
+
+// pathLossExponent determines how the range scales with the link budget, it matches the path loss model of BleTransmission
+const pathLossExponent = 2.5
+
+// rangeFactor returns how much the range is scaled by the given gain in dB over the link budget of typical nodes
+func rangeFactor(gain float64) float64 {
+	return math.Pow(10, gain/(10*pathLossExponent))
+}
+
+// linkRangeSquared returns the squared distance within which packets from origin are received by target,
+// given the squared range between typical nodes
+func linkRangeSquared(nodeRangeSquared float64, origin *InternalNode, target *InternalNode) float64 {
+	factor := origin.transmitFactor * target.receiveFactor
+	return nodeRangeSquared * factor * factor
+}
+
+// contactRangeSquared returns the squared distance within which the nodes connect, which is when either of them can hear the other
+func contactRangeSquared(nodeRangeSquared float64, nodeA *InternalNode, nodeB *InternalNode) float64 {
+	return max(linkRangeSquared(nodeRangeSquared, nodeA, nodeB), linkRangeSquared(nodeRangeSquared, nodeB, nodeA))
+}
+
+// lossFactorSquared returns the square of the factor by which a loss in dB scales the range
+func lossFactorSquared(loss float64) float64 {
+	if loss == 0 {
+		return 1
+	}
+	if math.IsInf(loss, 1) {
+		return 0
+	}
+	factor := rangeFactor(-loss)
+	return factor * factor
+}
+
+// floorLoss returns the loss in dB of the floor slabs between a and b
+func (s *Simulator) floorLoss(a Coordinate, b Coordinate) float64 {
+	floors := a.FloorsBetween(b)
+	if floors == 0 {
+		return 0
+	}
+	return s.options.FloorAttenuation * float64(floors)
+}
+
+// transmission returns the outcome of the transmission behavior between the coordinates, passing the floor loss to behaviors that account for it
+func (s *Simulator) transmission(behavior TransmissionBehavior, originCoord Coordinate, targetCoord Coordinate, packet []byte) (shouldBeDropped bool, delay time.Duration) {
+	if floorBehavior, ok := behavior.(FloorTransmissionBehavior); ok {
+		return floorBehavior.TransmissionThroughFloors(originCoord, targetCoord, s.floorLoss(originCoord, targetCoord), packet)
+	}
+	return behavior.Transmission(originCoord, targetCoord, packet)
+}
+
+// floorFactorSquared returns the square of the factor by which the floor slabs between a and b scale the range
+func floorFactorSquared(floorAttenuation float64, a Coordinate, b Coordinate) float64 {
+	floors := a.FloorsBetween(b)
+	if floors == 0 {
+		return 1
+	}
+	return lossFactorSquared(floorAttenuation * float64(floors))
+}
+
+// inLinkRange reports whether packets from origin currently reach target.
+// Links between nodes with the same range are symmetric, and are always usable while the nodes are connected,
+// unless a partition has separated them.
+func (s *Simulator) inLinkRange(origin *InternalNode, target *InternalNode) bool {
+	if s.regionMap.separated(origin, target) {
+		return false
+	}
+	rangeSquared := linkRangeSquared(s.nodeRange, origin, target)
+	if rangeSquared >= contactRangeSquared(s.nodeRange, origin, target) {
+		return true
+	}
+	if s.options.ContactPrediction {
+		s.refreshPosition(origin)
+		s.refreshPosition(target)
+	}
+	rangeSquared *= floorFactorSquared(s.options.FloorAttenuation, origin.coords, target.coords)
+	if s.options.Topography != nil {
+		rangeSquared *= obstacleFactorSquared(s.options.Topography, origin.coords, target.coords)
+	}
+	return nodeDistSquared(origin, target) < rangeSquared
+}
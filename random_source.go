@@ -0,0 +1,49 @@
+package simulator
+
+import "math/rand"
+
+// A RandomSource is a random source whose state can be saved in a checkpoint, see Simulator.TrackRandomSource.
+// It produces the same numbers as rand.NewSource, and its state is the seed and the number of values drawn since.
+type RandomSource struct {
+	source rand.Source64
+	seed   int64
+	draws  uint64
+}
+
+func NewRandomSource(seed int64) *RandomSource {
+	return &RandomSource{
+		source: rand.NewSource(seed).(rand.Source64),
+		seed:   seed,
+		draws:  0,
+	}
+}
+
+func (r *RandomSource) Int63() int64 {
+	r.draws++
+	return r.source.Int63()
+}
+
+func (r *RandomSource) Uint64() uint64 {
+	r.draws++
+	return r.source.Uint64()
+}
+
+func (r *RandomSource) Seed(seed int64) {
+	r.source.Seed(seed)
+	r.seed = seed
+	r.draws = 0
+}
+
+// restore reseeds the source and draws values until it reaches the given state
+func (r *RandomSource) restore(seed int64, draws uint64) {
+	r.Seed(seed)
+	for r.draws < draws {
+		r.Uint64()
+	}
+}
+
+// TrackRandomSource adds a random source whose state is saved in checkpoints and restored with them.
+// The sources must be tracked in the same order in the simulator that a checkpoint is restored into.
+func (s *Simulator) TrackRandomSource(source *RandomSource) {
+	s.randomSources = append(s.randomSources, source)
+}
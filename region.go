@@ -1,11 +1,25 @@
 package simulator
 
-import "slices"
+import (
+	"math"
+	"slices"
+)
 
 type RegionMap struct {
 	regionMap        map[RegionCoord]*Region
 	nodeRangeSquared float64
 	regionSize       float64
+	// searchRadius is the number of regions in each direction that can contain nodes within range,
+	// it grows as nodes with a larger range than the default are registered
+	searchRadius int
+	maxTransmit  float64
+	maxReceive   float64
+	topography   *Topography
+	// floorAttenuation is the loss in dB of each floor slab. Regions span all floors,
+	// as the floors above and below a node can be within range.
+	floorAttenuation float64
+	// partitions are the active partitions, which separate nodes regardless of their distance
+	partitions []*scheduledPartition
 }
 
 func NewRegionMap(nodeRange float64) *RegionMap {
@@ -13,6 +27,9 @@ func NewRegionMap(nodeRange float64) *RegionMap {
 		regionMap:        make(map[RegionCoord]*Region),
 		nodeRangeSquared: nodeRange * nodeRange,
 		regionSize:       nodeRange,
+		searchRadius:     1,
+		maxTransmit:      1,
+		maxReceive:       1,
 	}
 }
 
@@ -25,8 +42,8 @@ func (r *RegionMap) WithinRange(node *InternalNode) ([]*InternalNode, []*Interna
 	coords := r.CoordToRegionCoord(node.coords)
 	nodesWithinRange := []*InternalNode{}
 	peersOutOfRange := []*InternalNode{}
-	for _, x := range []int{-1, 0, 1} {
-		for _, y := range []int{-1, 0, 1} {
+	for x := -r.searchRadius; x <= r.searchRadius; x++ {
+		for y := -r.searchRadius; y <= r.searchRadius; y++ {
 			tmpCoords := RegionCoord{coords.X + x, coords.Y + y}
 			_, found := r.regionMap[tmpCoords]
 			if found {
@@ -34,7 +51,7 @@ func (r *RegionMap) WithinRange(node *InternalNode) ([]*InternalNode, []*Interna
 					if node.internalID == nodeB.internalID {
 						continue
 					}
-					if nodeDistSquared(node, nodeB) < r.nodeRangeSquared {
+					if r.withinContactRange(node, nodeB) {
 						nodesWithinRange = append(nodesWithinRange, nodeB)
 					} else {
 						if node.hasNeighbour(nodeB) {
@@ -51,6 +68,34 @@ func (r *RegionMap) WithinRange(node *InternalNode) ([]*InternalNode, []*Interna
 	return nodesWithinRange, peersOutOfRange
 }
 
+// withinContactRange reports whether the nodes can connect, taking the floors, obstacles and partitions between them into account.
+// Nodes that have crashed cannot connect.
+func (r *RegionMap) withinContactRange(nodeA *InternalNode, nodeB *InternalNode) bool {
+	return !nodeA.crashed && !nodeB.crashed && withinContactRange(r.nodeRangeSquared, r.topography, r.floorAttenuation, nodeA, nodeB) && !r.separated(nodeA, nodeB)
+}
+
+// RegionsAround returns the regions searched by WithinRange for any of the given nodes
+func (r *RegionMap) RegionsAround(nodes []*InternalNode) map[RegionCoord]bool {
+	regions := make(map[RegionCoord]bool)
+	for _, node := range nodes {
+		coords := r.CoordToRegionCoord(node.coords)
+		for x := -r.searchRadius; x <= r.searchRadius; x++ {
+			for y := -r.searchRadius; y <= r.searchRadius; y++ {
+				regions[RegionCoord{coords.X + x, coords.Y + y}] = true
+			}
+		}
+	}
+	return regions
+}
+
+// registerRange widens the search for nodes within range, such that it covers the range of the given node
+func (r *RegionMap) registerRange(node *InternalNode) {
+	r.maxTransmit = max(r.maxTransmit, node.transmitFactor)
+	r.maxReceive = max(r.maxReceive, node.receiveFactor)
+	maxRange := math.Sqrt(r.nodeRangeSquared) * r.maxTransmit * r.maxReceive
+	r.searchRadius = max(1, int(math.Ceil(maxRange/r.regionSize)))
+}
+
 func (r *RegionMap) AddNode(node *InternalNode) {
 	coord := r.CoordToRegionCoord(node.coords)
 	_, found := r.regionMap[coord]
@@ -60,6 +105,14 @@ func (r *RegionMap) AddNode(node *InternalNode) {
 	r.regionMap[coord].AddNode(node)
 }
 
+func (r *RegionMap) RemoveNode(node *InternalNode) {
+	coord := r.CoordToRegionCoord(node.coords)
+	region, found := r.regionMap[coord]
+	if found {
+		region.RemoveNode(node)
+	}
+}
+
 func (r *RegionMap) MoveNode(node *InternalNode, old Coordinate, new Coordinate) {
 	oldCoords := r.CoordToRegionCoord(old)
 	newCoord := r.CoordToRegionCoord(new)
@@ -0,0 +1,424 @@
+package simulator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// A replayRecord is an event read from an event log, together with the node positions at the time it was processed
+type replayRecord struct {
+	event     Event
+	positions []replayPosition
+}
+
+// replayNode is a placeholder for a node in a replayed simulation, it does not run any protocol
+type replayNode struct {
+	id NodeID
+}
+
+func (n *replayNode) OnConnect(peer Peer, id NodeID)                      {}
+func (n *replayNode) OnDisconnect(peer Peer, id NodeID)                   {}
+func (n *replayNode) OnReceivePacket(peer Peer, packet []byte, id NodeID) {}
+func (n *replayNode) OnStart(sim NodeArguments)                           {}
+func (n *replayNode) ID() NodeID                                          { return n.id }
+func (n *replayNode) OnTerminate()                                        {}
+func (n *replayNode) TransmissionBehavior() TransmissionBehavior          { return nil }
+
+// NewReplaySimulator constructs a simulator that replays an event log written by an EventLogWriter.
+// The nodes are not executed again, instead the recorded events are passed to the loggers
+// and used to update the positions and connections of placeholder nodes, such that the replay can be visualized.
+// The records are read from r one at a time while they are replayed, an invalid record terminates the replay with an error.
+func NewReplaySimulator(r io.Reader, loggers []Logger) (*Simulator, error) {
+	s := NewSimulator(1, 0, rand.New(rand.NewSource(0)), loggers, nil)
+	s.replaying = true
+
+	reader := &eventLogReader{r: bufio.NewReader(r)}
+	if err := reader.header(); err != nil {
+		return nil, err
+	}
+	s.replay = &eventLogReplay{
+		reader:     reader,
+		sim:        s,
+		nodes:      make(map[InternalID]*InternalNode),
+		partitions: make(map[int]*scheduledPartition),
+	}
+	return s, nil
+}
+
+// An eventLogReplay reads the records of an event log one at a time, as they are replayed
+type eventLogReplay struct {
+	reader     *eventLogReader
+	sim        *Simulator
+	nodes      map[InternalID]*InternalNode
+	partitions map[int]*scheduledPartition
+	lastEvent  Event
+	// next is the record that has been read but not yet replayed
+	next *replayRecord
+	err  error
+}
+
+// peek returns the next record without consuming it, or nil at the end of the log
+func (r *eventLogReplay) peek() (*replayRecord, error) {
+	if r.next == nil && r.err == nil {
+		r.next, r.err = r.read()
+	}
+	return r.next, r.err
+}
+
+// pop consumes the record returned by peek
+func (r *eventLogReplay) pop() {
+	r.next = nil
+}
+
+func (r *eventLogReplay) node() (*InternalNode, error) {
+	internalID, err := r.reader.internalID()
+	if err != nil {
+		return nil, err
+	}
+	iNode, found := r.nodes[internalID]
+	if !found {
+		return nil, fmt.Errorf("event log refers to unknown node %d", internalID)
+	}
+	return iNode, nil
+}
+
+// read reads the next record from the event log, it returns nil at the end of the log
+func (r *eventLogReplay) read() (*replayRecord, error) {
+	eventType, err := r.reader.r.ReadByte()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	t, err := r.reader.duration()
+	if err != nil {
+		return nil, err
+	}
+	sequenceNumber, err := r.reader.varint()
+	if err != nil {
+		return nil, err
+	}
+	positions, err := r.reader.positions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the direct parent is kept, such that the replayed events do not hold on to the whole log
+	if r.lastEvent != nil {
+		r.lastEvent.base().parentEvent = nil
+	}
+	base := BaseEvent{
+		time:           t,
+		sequenceNumber: sequenceNumber,
+		parentEvent:    r.lastEvent,
+	}
+
+	var e Event
+	switch EventType(eventType) {
+	case TIMESTEP:
+		e = &TimestepEvent{BaseEvent: base}
+	case MOVEMENT:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		e = &MovementEvent{BaseEvent: base, node: iNode}
+	case CONNECT, DISCONNECT:
+		nodeA, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		nodeB, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		if EventType(eventType) == CONNECT {
+			e = &ConnectEvent{BaseEvent: base, nodeA: nodeA, nodeB: nodeB}
+		} else {
+			e = &DisconnectEvent{BaseEvent: base, nodeA: nodeA, nodeB: nodeB}
+		}
+	case ADD_NODE:
+		internalID, err := r.reader.internalID()
+		if err != nil {
+			return nil, err
+		}
+		id, err := r.reader.nodeID()
+		if err != nil {
+			return nil, err
+		}
+		coords, err := r.reader.coordinate()
+		if err != nil {
+			return nil, err
+		}
+		iNode := r.sim.newReplayNode(internalID, id, coords)
+		r.nodes[internalID] = iNode
+		e = &AddNodeEvent{BaseEvent: base, node: iNode}
+	case RM_NODE:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		e = &RemoveNodeEvent{BaseEvent: base, node: iNode}
+	case SEND_MSG:
+		origin, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		target, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		targetNodeID, err := r.reader.nodeID()
+		if err != nil {
+			return nil, err
+		}
+		dropped, err := r.reader.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		delay, err := r.reader.varint()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := r.reader.bytes()
+		if err != nil {
+			return nil, err
+		}
+		e = &SendEvent{
+			BaseEvent:       base,
+			target:          target,
+			origin:          origin,
+			targetNodeID:    targetNodeID,
+			peer:            Peer{target: target, origin: origin},
+			shouldBeDropped: dropped != 0,
+			delay:           time.Duration(delay),
+			packet:          packet,
+		}
+	case RCV_MSG:
+		target, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		origin, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		originNodeID, err := r.reader.nodeID()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := r.reader.bytes()
+		if err != nil {
+			return nil, err
+		}
+		e = &ReceiveEvent{
+			BaseEvent:    base,
+			target:       target,
+			origin:       origin,
+			originNodeID: originNodeID,
+			peer:         Peer{target: origin, origin: target},
+			packet:       packet,
+		}
+	case DELAY:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		e = &DelayEvent{BaseEvent: base, node: iNode}
+	case PARTITION:
+		p, err := r.reader.partition(t)
+		if err != nil {
+			return nil, err
+		}
+		r.partitions[p.index] = p
+		e = &PartitionEvent{BaseEvent: base, partition: p}
+	case HEAL:
+		index, err := r.reader.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		p, found := r.partitions[int(index)]
+		if !found {
+			return nil, fmt.Errorf("event log heals unknown partition %d", index)
+		}
+		e = &HealEvent{BaseEvent: base, partition: p}
+	case CRASH:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		downtime, err := r.reader.duration()
+		if err != nil {
+			return nil, err
+		}
+		e = &CrashEvent{BaseEvent: base, node: iNode, downtime: downtime}
+	case RESTART:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		e = &RestartEvent{BaseEvent: base, node: iNode}
+	case DEPLETE:
+		iNode, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		e = &DepleteEvent{BaseEvent: base, node: iNode}
+	case BUFFER_DROP:
+		origin, err := r.node()
+		if err != nil {
+			return nil, err
+		}
+		targetNodeID, err := r.reader.nodeID()
+		if err != nil {
+			return nil, err
+		}
+		queued, err := r.reader.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := r.reader.bytes()
+		if err != nil {
+			return nil, err
+		}
+		e = &BufferDropEvent{BaseEvent: base, origin: origin, targetNodeID: targetNodeID, packet: packet, queued: queued != 0}
+	case TERMINATE:
+		message, err := r.reader.bytes()
+		if err != nil {
+			return nil, err
+		}
+		var terminateErr error
+		if len(message) > 0 {
+			terminateErr = errors.New(string(message))
+		}
+		e = &TerminateEvent{BaseEvent: base, err: terminateErr}
+	default:
+		return nil, fmt.Errorf("unknown event type %d in event log", eventType)
+	}
+
+	r.lastEvent = e
+	return &replayRecord{e, positions}, nil
+}
+
+func (s *Simulator) newReplayNode(internalID InternalID, id NodeID, coords Coordinate) *InternalNode {
+	return &InternalNode{
+		node:                &replayNode{id: id},
+		nodeID:              id,
+		internalID:          internalID,
+		coords:              coords,
+		segmentStart:        coords,
+		movementInstruction: MovementInstruction{Coordinate{X: 0, Y: 0}, -1},
+		peers:               make(map[InternalID]Peer),
+		connectedAt:         make(map[InternalID]time.Duration),
+		random:              s.random,
+		sim:                 s,
+		transmitFactor:      1,
+		receiveFactor:       1,
+		data:                make(map[string]interface{}),
+	}
+}
+
+// replayUntil passes the recorded events up until the given time to the loggers,
+// in the order they were originally processed.
+func (s *Simulator) replayUntil(updateUntilTime time.Duration, stop func() bool) (bool, error) {
+	nodes := make(map[InternalID]*InternalNode)
+	for _, iNode := range s.nodes {
+		nodes[iNode.internalID] = iNode
+	}
+
+	for {
+		record, err := s.replay.peek()
+		if err != nil {
+			return true, err
+		}
+		if record == nil || record.event.Time() > updateUntilTime {
+			break
+		}
+		s.replay.pop()
+
+		// The positions are applied before the loggers are updated, such that they observe the same state as in the recorded run
+		for _, position := range record.positions {
+			if iNode, found := nodes[position.internalID]; found {
+				s.moveNodeTo(iNode, position.coords)
+			}
+		}
+
+		e := record.event
+		s.time = e.Time()
+		s.updateLoggers(e)
+		s.lastEvent = e
+
+		if e.EventType() == ADD_NODE {
+			iNode := e.(*AddNodeEvent).node
+			nodes[iNode.internalID] = iNode
+		}
+		if terminated, err := s.replayEvent(e); terminated {
+			return true, err
+		}
+
+		if stop != nil && stop() {
+			break
+		}
+	}
+	return false, nil
+}
+
+// replayEvent applies a recorded event to the replayed nodes. It returns true when the replay has terminated.
+func (s *Simulator) replayEvent(e Event) (bool, error) {
+	switch e.EventType() {
+	case CONNECT:
+		connectEvent := e.(*ConnectEvent)
+		if !connectEvent.nodeA.hasNeighbour(connectEvent.nodeB) {
+			s.peers.add(connectEvent.nodeA, connectEvent.nodeB)
+			s.connectNodes(connectEvent.nodeA, connectEvent.nodeB)
+		}
+	case DISCONNECT:
+		disconnectEvent := e.(*DisconnectEvent)
+		if disconnectEvent.nodeA.hasNeighbour(disconnectEvent.nodeB) {
+			s.peers.remove(disconnectEvent.nodeA, disconnectEvent.nodeB)
+			s.disconnectNodes(disconnectEvent.nodeA, disconnectEvent.nodeB)
+		}
+	case ADD_NODE:
+		iNode := e.(*AddNodeEvent).node
+		s.nodes = append(s.nodes, iNode)
+		s.regionMap.AddNode(iNode)
+	case RM_NODE:
+		s.replayRemoveNode(e.(*RemoveNodeEvent).node)
+	case DEPLETE:
+		s.replayRemoveNode(e.(*DepleteEvent).node)
+	case PARTITION:
+		s.regionMap.partitions = append(s.regionMap.partitions, e.(*PartitionEvent).partition)
+	case HEAL:
+		p := e.(*HealEvent).partition
+		s.regionMap.partitions = slices.DeleteFunc(s.regionMap.partitions, func(other *scheduledPartition) bool {
+			return other == p
+		})
+	case CRASH:
+		e.(*CrashEvent).node.crashed = true
+	case RESTART:
+		e.(*RestartEvent).node.crashed = false
+	case TERMINATE:
+		s.isTerminating = true
+		s.isRunning = false
+		return true, e.(*TerminateEvent).err
+	}
+	return false, nil
+}
+
+func (s *Simulator) replayRemoveNode(iNode *InternalNode) {
+	for _, peer := range iNode.peers {
+		peer.target.disconnectNodes(iNode)
+	}
+	iNode.peers = make(map[InternalID]Peer)
+	s.peers.removeNode(iNode)
+	s.nodes = slices.DeleteFunc(s.nodes, func(n *InternalNode) bool {
+		return n == iNode
+	})
+	s.regionMap.RemoveNode(iNode)
+}
@@ -0,0 +1,202 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RunOptions decides when Run stops. The zero value of each field disables the condition.
+type RunOptions struct {
+	// Until is the simulated time to run until
+	Until time.Duration
+	// Timeout is the wall-clock time after which the run stops
+	Timeout time.Duration
+	// MaxEvents is the number of events to process before stopping
+	MaxEvents int
+	// StopWhen contains predicates which are checked after each event, the run stops when one of them returns true
+	StopWhen []func(s *Simulator) bool
+	// Speed paces the run so the simulated time advances Speed times as fast as the wall clock,
+	// the run does not stop when it is idle since simulated time keeps passing
+	Speed float64
+	// StallThreshold is the lag behind the wall clock after which a paced run reports a stall, DefaultStallThreshold is used if it is 0
+	StallThreshold time.Duration
+	// OnStall is called with the lag after each update that leaves a paced run behind the wall clock
+	OnStall func(s *Simulator, lag time.Duration)
+}
+
+func DefaultRunOptions() *RunOptions {
+	return &RunOptions{
+		Until:     0,
+		Timeout:   0,
+		MaxEvents: 0,
+		StopWhen:  nil,
+
+		Speed:          0,
+		StallThreshold: 0,
+		OnStall:        nil,
+	}
+}
+
+// A StopReason tells why Run stopped
+type StopReason int
+
+const (
+	// StopUntil means that the simulated time reached RunOptions.Until
+	StopUntil StopReason = iota
+	// StopTerminated means that the simulation terminated
+	StopTerminated
+	// StopIdle means that there are no more events to process
+	StopIdle
+	// StopTimeout means that RunOptions.Timeout elapsed
+	StopTimeout
+	// StopCancelled means that the context was cancelled
+	StopCancelled
+	// StopMaxEvents means that RunOptions.MaxEvents events were processed
+	StopMaxEvents
+	// StopPredicate means that one of RunOptions.StopWhen returned true
+	StopPredicate
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopUntil:
+		return "until"
+	case StopTerminated:
+		return "terminated"
+	case StopIdle:
+		return "idle"
+	case StopTimeout:
+		return "timeout"
+	case StopCancelled:
+		return "cancelled"
+	case StopMaxEvents:
+		return "max_events"
+	case StopPredicate:
+		return "predicate"
+	default:
+		return fmt.Sprintf("StopReason(%d)", int(r))
+	}
+}
+
+// RunResult describes why and when Run stopped
+type RunResult struct {
+	Reason StopReason
+	// Predicate is the index of the predicate in RunOptions.StopWhen that stopped the run, or -1
+	Predicate int
+	// Time is the simulated time when the run stopped
+	Time time.Duration
+	// Events is the number of events processed by the run
+	Events int
+	// RealTime is the wall-clock time the run took
+	RealTime time.Duration
+	// Err is the error the simulation terminated with, or the error of the context if it was cancelled
+	Err error
+	// Stalls is the number of times a paced run fell behind the wall clock
+	Stalls int
+	// MaxLag is the largest lag behind the wall clock of a paced run
+	MaxLag time.Duration
+}
+
+// Run processes events until one of the conditions of the options is met, or the simulation terminates.
+// The simulation is not terminated when another condition stops it, so it can be continued by calling Run or Update again,
+// or stopped cleanly by calling Terminate. Loggers implementing FlushingLogger are flushed when Run returns.
+// If options is nil, DefaultRunOptions is used.
+func (s *Simulator) Run(ctx context.Context, options *RunOptions) RunResult {
+	if options == nil {
+		options = DefaultRunOptions()
+	}
+
+	start := time.Now()
+	result := RunResult{Reason: StopUntil, Predicate: -1}
+	if err := ctx.Err(); err != nil {
+		result.Reason = StopCancelled
+		result.Err = err
+		result.Time = s.time
+		return result
+	}
+
+	until := time.Duration(math.MaxInt64)
+	if options.Until > 0 {
+		until = options.Until
+	}
+
+	stopped := false
+	expired := func() bool {
+		if err := ctx.Err(); err != nil {
+			result.Reason = StopCancelled
+			result.Err = err
+			stopped = true
+		} else if options.Timeout > 0 && time.Since(start) >= options.Timeout {
+			result.Reason = StopTimeout
+			stopped = true
+		}
+		return stopped
+	}
+	stop := func() bool {
+		result.Events++
+		for i, predicate := range options.StopWhen {
+			if predicate(s) {
+				result.Reason = StopPredicate
+				result.Predicate = i
+				stopped = true
+				return true
+			}
+		}
+		if options.MaxEvents > 0 && result.Events >= options.MaxEvents {
+			result.Reason = StopMaxEvents
+			stopped = true
+			return true
+		}
+		return expired()
+	}
+
+	var terminated bool
+	var err error
+	if options.Speed > 0 {
+		pacer := s.NewPacer(options.Speed)
+		if options.StallThreshold > 0 {
+			pacer.SetStallThreshold(options.StallThreshold)
+		}
+		pacer.OnStall(options.OnStall)
+		for {
+			terminated, err = pacer.update(until, stop)
+			if terminated || stopped || pacer.Time() >= until || expired() {
+				break
+			}
+			pacer.wait(ctx, until)
+		}
+		result.Stalls = pacer.Stalls()
+		result.MaxLag = pacer.MaxLag()
+	} else {
+		terminated, err = s.processEvents(until, stop)
+	}
+	if terminated {
+		result.Reason = StopTerminated
+		result.Err = err
+	} else if !stopped && options.Speed <= 0 && !s.hasPendingEvents() {
+		result.Reason = StopIdle
+	}
+	if err := s.flushLoggers(); err != nil && result.Err == nil {
+		result.Err = err
+	}
+
+	result.Time = s.time
+	result.RealTime = time.Since(start)
+	return result
+}
+
+// hasPendingEvents reports whether there are events left to process
+func (s *Simulator) hasPendingEvents() bool {
+	if s.replaying {
+		record, err := s.replay.peek()
+		return record != nil || err != nil
+	}
+	for _, e := range *s.eventQueue {
+		if !e.base().cancelled {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,957 @@
+package scenario_config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/connection_behavior"
+	"github.com/starling-protocol/simulator/crash_behavior"
+	"github.com/starling-protocol/simulator/energy_profiles"
+	"github.com/starling-protocol/simulator/loggers"
+	"github.com/starling-protocol/simulator/movement_profiles"
+	node "github.com/starling-protocol/simulator/starling_node"
+	"github.com/starling-protocol/simulator/transmission_behavior"
+	"github.com/starling-protocol/simulator/vadere"
+	"github.com/starling-protocol/starling/device"
+	"github.com/starling-protocol/starling/utils"
+)
+
+// Simulation is a simulator built from a config, with its nodes ready to be started
+type Simulation struct {
+	Simulator *simulator.Simulator
+	Seed      int64
+	// Nodes contains the nodes by name, the nodes of a group are named name[i]
+	Nodes map[string]*node.Node
+	// Order contains the names of the nodes in the order they were added
+	Order []string
+	// Sends contains the send scenarios of all nodes
+	Sends []*node.ScenarioSendData
+	// RunOptions stops the run as described by the config
+	RunOptions *simulator.RunOptions
+}
+
+// Build constructs the simulation described by the config, using the configured seed if given
+func (c *Config) Build() (*Simulation, error) {
+	seed := rand.Int63()
+	if c.Seed != nil {
+		seed = *c.Seed
+	}
+	return c.BuildWithSeed(seed)
+}
+
+// BuildWithSeed constructs the simulation described by the config, using the given seed
+func (c *Config) BuildWithSeed(seed int64) (*Simulation, error) {
+	randomSource := simulator.NewRandomSource(seed)
+	b := &builder{
+		config:       c,
+		randomSource: randomSource,
+		random:       rand.New(randomSource),
+		nodes:        make(map[string]*node.Node),
+		groups:       make(map[string][]string),
+		contacts:     make(map[string]device.ContactID),
+		sessions:     make(map[string]*node.SessionOrchestrator),
+		usedIDs:      make(map[simulator.NodeID]bool),
+		nextID:       0,
+		simulation:   &Simulation{Seed: seed, Nodes: make(map[string]*node.Node), Order: []string{}, Sends: []*node.ScenarioSendData{}},
+		nodeConfigs:  make(map[string]*NodeConfig),
+	}
+
+	if err := b.build(); err != nil {
+		return nil, err
+	}
+	return b.simulation, nil
+}
+
+type builder struct {
+	config *Config
+	// randomSource is the source of random, which is shared by everything in the simulation and saved in checkpoints
+	randomSource *simulator.RandomSource
+	random       *rand.Rand
+	sim          *simulator.Simulator
+	simulation   *Simulation
+
+	nodes       map[string]*node.Node
+	nodeConfigs map[string]*NodeConfig
+	groups      map[string][]string
+	contacts    map[string]device.ContactID
+	sessions    map[string]*node.SessionOrchestrator
+	usedIDs     map[simulator.NodeID]bool
+	nextID      simulator.NodeID
+	projection  simulator.Projection
+}
+
+func (b *builder) build() error {
+	if b.config.BLERange <= 0 {
+		return errors.New("bleRange must be positive")
+	}
+
+	if b.config.Projection != nil {
+		projection, err := newProjection(b.config.Projection)
+		if err != nil {
+			return err
+		}
+		b.projection = projection
+	}
+
+	loggerList := []simulator.Logger{}
+	for _, loggerConfig := range b.config.Loggers {
+		logger, err := b.newLogger(loggerConfig)
+		if err != nil {
+			return err
+		}
+		loggerList = append(loggerList, logger)
+	}
+
+	options := simulator.DefaultSimulatorOptions()
+	if b.config.Simulator != nil {
+		if b.config.Simulator.TimeStep > 0 {
+			options.TimeStep = time.Duration(b.config.Simulator.TimeStep)
+		}
+		options.ContactPrediction = b.config.Simulator.ContactPrediction
+		if b.config.Simulator.FloorAttenuation != nil {
+			options.FloorAttenuation = *b.config.Simulator.FloorAttenuation
+		}
+	}
+	options.Projection = b.projection
+	if b.config.Bandwidth != nil {
+		bandwidth, err := b.bandwidthModel(b.config.Bandwidth)
+		if err != nil {
+			return err
+		}
+		options.Bandwidth = bandwidth
+	}
+	if b.config.Discovery != nil {
+		discovery, err := discoveryModel(b.config.Discovery)
+		if err != nil {
+			return err
+		}
+		options.Discovery = discovery
+	}
+	if b.config.Obstacles != nil {
+		if b.config.Topography == "" {
+			return errors.New("obstacles need a topography")
+		}
+		attenuation := b.config.Obstacles.Attenuation
+		if attenuation == 0 {
+			attenuation = math.Inf(1)
+		}
+		topography, err := vadere.LoadTopography(b.config.Topography, attenuation)
+		if err != nil {
+			return err
+		}
+		options.Topography = topography
+	}
+	if b.config.Connection != nil {
+		connection, err := b.connectionBehavior(b.config.Connection)
+		if err != nil {
+			return err
+		}
+		options.Connection = connection
+	}
+
+	b.sim = simulator.NewSimulator(b.config.BLERange, time.Duration(b.config.TransmissionDelay), b.random, loggerList, options)
+	b.sim.TrackRandomSource(b.randomSource)
+	b.simulation.Simulator = b.sim
+
+	for i := range b.config.Nodes {
+		if err := b.addNodes(&b.config.Nodes[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, contactConfig := range b.config.Contacts {
+		if err := b.addContact(contactConfig); err != nil {
+			return err
+		}
+	}
+
+	for i, partitionConfig := range b.config.Partitions {
+		if err := b.schedulePartition(partitionConfig); err != nil {
+			return fmt.Errorf("partition %d: %w", i, err)
+		}
+	}
+
+	for _, sessionConfig := range b.config.Sessions {
+		contact, found := b.contacts[sessionConfig.Contact]
+		if !found {
+			return fmt.Errorf("session %s refers to unknown contact %s", sessionConfig.Name, sessionConfig.Contact)
+		}
+		if _, found := b.sessions[sessionConfig.Name]; found {
+			return fmt.Errorf("session %s is defined twice", sessionConfig.Name)
+		}
+		b.sessions[sessionConfig.Name] = node.NewSessionOrchestrator(contact)
+	}
+
+	for _, name := range b.simulation.Order {
+		nodeConfig := b.nodeConfigs[name]
+		scope := &scenarioScope{
+			sessions: make(map[string]*node.ScenarioSession),
+			sends:    make(map[string]*node.ScenarioSendData),
+		}
+		for _, scenarioConfig := range nodeConfig.Scenarios {
+			scenario, err := b.scenario(scenarioConfig, scope, true)
+			if err != nil {
+				return fmt.Errorf("node %s: %w", name, err)
+			}
+			b.nodes[name].AddScenario(scenario)
+		}
+	}
+
+	if b.config.MaxEvents < 0 {
+		return errors.New("maxEvents must not be negative")
+	}
+	if b.config.Speed < 0 {
+		return errors.New("speed must not be negative")
+	}
+	b.simulation.RunOptions = &simulator.RunOptions{
+		Until:          time.Duration(b.config.Duration),
+		Timeout:        time.Duration(b.config.Timeout),
+		MaxEvents:      b.config.MaxEvents,
+		Speed:          b.config.Speed,
+		StallThreshold: time.Duration(b.config.StallThreshold),
+	}
+	if b.config.StopWhenDelivered {
+		if len(b.simulation.Sends) == 0 {
+			return errors.New("stopWhenDelivered needs send scenarios")
+		}
+		b.simulation.RunOptions.StopWhen = append(b.simulation.RunOptions.StopWhen, node.AllDelivered(b.simulation.Sends...))
+	}
+
+	return nil
+}
+
+func (b *builder) addNodes(nodeConfig *NodeConfig) error {
+	if nodeConfig.Name == "" {
+		return errors.New("nodes must have a name")
+	}
+	if _, found := b.groups[nodeConfig.Name]; found {
+		return fmt.Errorf("node %s is defined twice", nodeConfig.Name)
+	}
+
+	protocolOptions, err := b.protocolOptions(nodeConfig.Protocol)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+	}
+
+	transmissionConfig := nodeConfig.Transmission
+	if transmissionConfig == nil {
+		transmissionConfig = b.config.Transmission
+	}
+	transmissionBehavior, err := b.transmissionBehavior(transmissionConfig)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+	}
+
+	bufferConfig := nodeConfig.Buffer
+	if bufferConfig == nil {
+		bufferConfig = b.config.Buffer
+	}
+	nodeOptions, err := nodeOptions(bufferConfig)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+	}
+	nodeOptions.TransmitPowerOffset = nodeConfig.TransmitPowerOffset
+	nodeOptions.ReceiverSensitivityOffset = nodeConfig.ReceiverSensitivityOffset
+	nodeOptions.MaxConnections = nodeConfig.MaxConnections
+	nodeOptions.PeerSelection, err = peerSelection(nodeConfig.PeerSelection)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+	}
+	if nodeConfig.Bandwidth != nil {
+		nodeOptions.Bandwidth, err = b.bandwidthModel(nodeConfig.Bandwidth)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+		}
+	}
+	if nodeConfig.Crashes != nil {
+		if nodeConfig.Crashes.MeanUptime <= 0 {
+			return fmt.Errorf("node %s: crashes must have a positive mean uptime", nodeConfig.Name)
+		}
+		nodeOptions.Crashes = crash_behavior.RandomCrashes{
+			MeanUptime:   time.Duration(nodeConfig.Crashes.MeanUptime),
+			MeanDowntime: time.Duration(nodeConfig.Crashes.MeanDowntime),
+			Random:       b.random,
+		}
+	}
+	nodeOptions.PersistState = nodeConfig.PersistState
+	energyConfig := nodeConfig.Energy
+	if energyConfig == nil {
+		energyConfig = b.config.Energy
+	}
+	if energyConfig != nil {
+		nodeOptions.Energy, err = energyProfile(energyConfig)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+		}
+	}
+	if clockConfig := nodeConfig.Clock; clockConfig != nil && (clockConfig.OffsetSpread < 0 || clockConfig.DriftSpread < 0) {
+		return fmt.Errorf("node %s: clock spreads must not be negative", nodeConfig.Name)
+	}
+	if clockConfig := nodeConfig.Clock; clockConfig != nil && clockConfig.Drift-clockConfig.DriftSpread <= -1e6 {
+		return fmt.Errorf("node %s: clock drift must be greater than -1000000 ppm", nodeConfig.Name)
+	}
+	if nodeConfig.CrashAt != nil && nodeConfig.RestartAfter <= 0 {
+		return fmt.Errorf("node %s: crashed nodes must restart after a positive duration", nodeConfig.Name)
+	}
+
+	if nodeConfig.ID != nil {
+		b.nextID = simulator.NodeID(*nodeConfig.ID)
+	}
+
+	newNode := func(name string, movement simulator.NodeMovement, delay time.Duration, removeAt *time.Duration) error {
+		if _, found := b.nodes[name]; found {
+			return fmt.Errorf("node %s is defined twice", name)
+		}
+		for b.usedIDs[b.nextID] && nodeConfig.ID == nil {
+			b.nextID++
+		}
+		if b.usedIDs[b.nextID] {
+			return fmt.Errorf("node %s has the ID %d, which is already used", name, b.nextID)
+		}
+
+		nodeID := b.nextID
+		b.usedIDs[nodeID] = true
+		b.nextID++
+
+		n := node.NewNode(b.random, &nodeID, transmissionBehavior, protocolOptions)
+		if nodeConfig.Colors {
+			node.NetworkLayerColors(n)
+		}
+		options := nodeOptions
+		if nodeConfig.Clock != nil {
+			clockOptions := *nodeOptions
+			clockOptions.Clock = b.clock(nodeConfig.Clock)
+			options = &clockOptions
+		}
+		b.sim.AddNodeWithOptions(n, movement, delay, options)
+		if removeAt != nil {
+			b.sim.RemoveNode(n, *removeAt)
+		}
+		if nodeConfig.CrashAt != nil {
+			b.sim.ScheduleCrash(nodeID, time.Duration(*nodeConfig.CrashAt), time.Duration(nodeConfig.RestartAfter))
+		}
+
+		b.nodes[name] = n
+		b.nodeConfigs[name] = nodeConfig
+		b.groups[nodeConfig.Name] = append(b.groups[nodeConfig.Name], name)
+		b.simulation.Nodes[name] = n
+		b.simulation.Order = append(b.simulation.Order, name)
+		return nil
+	}
+
+	var removeAt *time.Duration
+	if nodeConfig.RemoveAt != nil {
+		t := time.Duration(*nodeConfig.RemoveAt)
+		removeAt = &t
+	}
+
+	if nodeConfig.Vadere != nil {
+		vadereNodeMap := vadere.VadereLoad(nodeConfig.Vadere.Path, nodeConfig.Vadere.KeepRate, b.random)
+		for i, vadereNodeID := range utils.ShuffleMapKeys(b.random, vadereNodeMap) {
+			vadereNode := vadereNodeMap[vadereNodeID]
+			vadereRemoveAt := removeAt
+			if vadereRemoveAt == nil {
+				vadereRemoveAt = vadereNode.EndTime
+			}
+			movement := vadere.NewMovementProfile(vadereNode.CoordList)
+			name := fmt.Sprintf("%s[%d]", nodeConfig.Name, i)
+			if err := newNode(name, movement, time.Duration(nodeConfig.Delay)+*vadereNode.StartTime, vadereRemoveAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	movementConfig := nodeConfig.Movement
+	if movementConfig == nil {
+		movementConfig = b.config.Movement
+	}
+
+	count := max(nodeConfig.Count, 1)
+	for i := 0; i < count; i++ {
+		movement, err := b.movement(movementConfig)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", nodeConfig.Name, err)
+		}
+
+		name := nodeConfig.Name
+		if nodeConfig.Count > 1 {
+			name = fmt.Sprintf("%s[%d]", nodeConfig.Name, i)
+		}
+		if err := newNode(name, movement, time.Duration(nodeConfig.Delay), removeAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveNodes returns the nodes referred to by a node name or a group name
+func (b *builder) resolveNodes(name string) ([]*node.Node, error) {
+	if n, found := b.nodes[name]; found {
+		return []*node.Node{n}, nil
+	}
+	if group, found := b.groups[name]; found {
+		nodes := []*node.Node{}
+		for _, member := range group {
+			nodes = append(nodes, b.nodes[member])
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("unknown node %s", name)
+}
+
+func (b *builder) addContact(contactConfig ContactConfig) error {
+	if _, found := b.contacts[contactConfig.Name]; found {
+		return fmt.Errorf("contact %s is defined twice", contactConfig.Name)
+	}
+
+	members := []*node.Node{}
+	for _, name := range contactConfig.Nodes {
+		nodes, err := b.resolveNodes(name)
+		if err != nil {
+			return fmt.Errorf("contact %s: %w", contactConfig.Name, err)
+		}
+		members = append(members, nodes...)
+	}
+
+	switch len(members) {
+	case 0, 1:
+		return fmt.Errorf("contact %s must have at least two nodes", contactConfig.Name)
+	case 2:
+		b.contacts[contactConfig.Name] = node.LinkNodes(members[0], members[1])
+	default:
+		b.contacts[contactConfig.Name] = node.GroupLinkNodes(members)
+	}
+	return nil
+}
+
+func (b *builder) schedulePartition(partitionConfig PartitionConfig) error {
+	start := time.Duration(partitionConfig.Start)
+	heal := time.Duration(partitionConfig.Heal)
+	if start < 0 || heal <= start {
+		return errors.New("partition must heal after it starts")
+	}
+	if len(partitionConfig.Cuts) > 0 && b.config.Simulator != nil && b.config.Simulator.ContactPrediction {
+		return errors.New("partition cuts are not supported with contact prediction")
+	}
+
+	partition := simulator.Partition{}
+	grouped := make(map[simulator.NodeID]bool)
+	for _, names := range partitionConfig.Groups {
+		group := []simulator.NodeID{}
+		for _, name := range names {
+			nodes, err := b.resolveNodes(name)
+			if err != nil {
+				return err
+			}
+			for _, n := range nodes {
+				if grouped[n.ID()] {
+					return fmt.Errorf("node %d is in more than one group", n.ID())
+				}
+				grouped[n.ID()] = true
+				group = append(group, n.ID())
+			}
+		}
+		partition.Groups = append(partition.Groups, group)
+	}
+	for _, cutConfig := range partitionConfig.Cuts {
+		from, err := b.coordinate(&cutConfig.From)
+		if err != nil {
+			return err
+		}
+		to, err := b.coordinate(&cutConfig.To)
+		if err != nil {
+			return err
+		}
+		partition.Cuts = append(partition.Cuts, simulator.Cut{From: from, To: to})
+	}
+
+	b.sim.SchedulePartition(start, heal, partition)
+	return nil
+}
+
+func (b *builder) protocolOptions(nodeProtocol *ProtocolConfig) (*device.ProtocolOptions, error) {
+	if b.config.Protocol == nil && nodeProtocol == nil {
+		return nil, nil
+	}
+
+	options := device.DefaultProtocolOptions()
+	for _, protocolConfig := range []*ProtocolConfig{b.config.Protocol, nodeProtocol} {
+		if protocolConfig == nil {
+			continue
+		}
+		if protocolConfig.EnableSync != nil {
+			options.EnableSync = *protocolConfig.EnableSync
+		}
+		if protocolConfig.DisableAutoRREQOnConnection != nil {
+			options.DisableAutoRREQOnConnection = *protocolConfig.DisableAutoRREQOnConnection
+		}
+		if protocolConfig.MaxRREQTTL != nil {
+			options.MaxRREQTTL = *protocolConfig.MaxRREQTTL
+		}
+		if protocolConfig.ForwardRREQsWhenMatching != nil {
+			options.ForwardRREQsWhenMatching = *protocolConfig.ForwardRREQsWhenMatching
+		}
+		if protocolConfig.ACKDelay != nil {
+			options.ACKDelay = time.Duration(*protocolConfig.ACKDelay)
+		}
+		if protocolConfig.ACKTimeout != nil {
+			options.ACKTimeout = time.Duration(*protocolConfig.ACKTimeout)
+		}
+		switch protocolConfig.RREQBroadcastStrategy {
+		case "":
+		case "all":
+			options.RREQBroadcastStrategy = device.BroadcastAll
+		case "log":
+			options.RREQBroadcastStrategy = device.BroadcastLogFunc
+		case "two":
+			options.RREQBroadcastStrategy = device.BroadcastTwo
+		default:
+			return nil, fmt.Errorf("unknown route request broadcast strategy %s", protocolConfig.RREQBroadcastStrategy)
+		}
+	}
+	return options, nil
+}
+
+func nodeOptions(bufferConfig *BufferConfig) (*simulator.NodeOptions, error) {
+	options := simulator.DefaultNodeOptions()
+	if bufferConfig == nil {
+		return options, nil
+	}
+
+	if bufferConfig.Size > 0 {
+		options.BufferSize = bufferConfig.Size
+	}
+
+	switch bufferConfig.Discipline {
+	case "", "drop_tail":
+		options.QueueDiscipline = simulator.DropTail{}
+	case "drop_head":
+		options.QueueDiscipline = simulator.DropHead{}
+	case "random_early_drop":
+		options.QueueDiscipline = simulator.RandomEarlyDrop{
+			MinThreshold:   bufferConfig.MinThreshold,
+			MaxThreshold:   bufferConfig.MaxThreshold,
+			MaxProbability: bufferConfig.MaxProbability,
+		}
+	case "priority":
+		options.QueueDiscipline = simulator.PriorityDrop{Classify: node.PacketClass}
+	default:
+		return nil, fmt.Errorf("unknown queue discipline %s", bufferConfig.Discipline)
+	}
+	return options, nil
+}
+
+func (b *builder) bandwidthModel(bandwidthConfig *BandwidthConfig) (simulator.BandwidthModel, error) {
+	switch bandwidthConfig.Type {
+	case "fixed":
+		if bandwidthConfig.BitsPerSecond <= 0 {
+			return nil, errors.New("fixed bandwidth needs a positive bitsPerSecond")
+		}
+		return simulator.FixedBandwidth{BitsPerSecond: bandwidthConfig.BitsPerSecond}, nil
+	case "ble":
+		return transmission_behavior.BleBandwidth{}, nil
+	default:
+		return nil, fmt.Errorf("unknown bandwidth model %s", bandwidthConfig.Type)
+	}
+}
+
+// clock returns a clock with a random offset and drift within the spreads of the config
+func (b *builder) clock(clockConfig *ClockConfig) simulator.Clock {
+	offset := time.Duration(clockConfig.Offset)
+	if clockConfig.OffsetSpread > 0 {
+		offset += time.Duration((b.random.Float64()*2 - 1) * float64(clockConfig.OffsetSpread))
+	}
+	drift := clockConfig.Drift
+	if clockConfig.DriftSpread > 0 {
+		drift += (b.random.Float64()*2 - 1) * clockConfig.DriftSpread
+	}
+	return simulator.Clock{Offset: offset, Drift: drift * 1e-6}
+}
+
+func energyProfile(energyConfig *EnergyConfig) (*simulator.EnergyProfile, error) {
+	profile := simulator.EnergyProfile{}
+	if energyConfig.Profile != "" {
+		var found bool
+		profile, found = energy_profiles.Profile(energyConfig.Profile)
+		if !found {
+			return nil, fmt.Errorf("unknown energy profile %s", energyConfig.Profile)
+		}
+	}
+
+	for _, value := range []struct {
+		config  *float64
+		profile *float64
+	}{
+		{energyConfig.Capacity, &profile.Capacity},
+		{energyConfig.TransmitPerByte, &profile.TransmitPerByte},
+		{energyConfig.ReceivePerByte, &profile.ReceivePerByte},
+		{energyConfig.ConnectedPower, &profile.ConnectedPower},
+		{energyConfig.ScanPower, &profile.ScanPower},
+	} {
+		if value.config == nil {
+			continue
+		}
+		if *value.config < 0 {
+			return nil, errors.New("energy costs must not be negative")
+		}
+		*value.profile = *value.config
+	}
+	return &profile, nil
+}
+
+func peerSelection(name string) (simulator.PeerSelection, error) {
+	switch name {
+	case "", "keep":
+		return simulator.KeepPeers{}, nil
+	case "nearest":
+		return simulator.NearestPeers{}, nil
+	case "random":
+		return simulator.RandomPeers{}, nil
+	case "oldest":
+		return simulator.OldestPeers{}, nil
+	case "protocol":
+		return simulator.ProtocolPeers{}, nil
+	default:
+		return nil, fmt.Errorf("unknown peer selection %s", name)
+	}
+}
+
+func discoveryModel(discoveryConfig *DiscoveryConfig) (simulator.DiscoveryModel, error) {
+	switch discoveryConfig.Type {
+	case "fixed":
+		return simulator.FixedDiscovery{Delay: time.Duration(discoveryConfig.Delay)}, nil
+	case "ble":
+		discovery := simulator.DefaultBleDiscovery()
+		if discoveryConfig.AdvertisingInterval > 0 {
+			discovery.AdvertisingInterval = time.Duration(discoveryConfig.AdvertisingInterval)
+		}
+		if discoveryConfig.ScanInterval > 0 {
+			discovery.ScanInterval = time.Duration(discoveryConfig.ScanInterval)
+		}
+		if discoveryConfig.ScanWindow > 0 {
+			discovery.ScanWindow = time.Duration(discoveryConfig.ScanWindow)
+		}
+		if discoveryConfig.ConnectionSetup > 0 {
+			discovery.ConnectionSetup = time.Duration(discoveryConfig.ConnectionSetup)
+		}
+		return discovery, nil
+	default:
+		return nil, fmt.Errorf("unknown discovery model %s", discoveryConfig.Type)
+	}
+}
+
+func (b *builder) connectionBehavior(connectionConfig *ConnectionConfig) (simulator.ConnectionBehavior, error) {
+	switch connectionConfig.Type {
+	case "reliable":
+		return connection_behavior.Reliable{Handshake: time.Duration(connectionConfig.Handshake)}, nil
+	case "random_failures":
+		return connection_behavior.RandomFailures{
+			FailureChance: connectionConfig.FailureChance,
+			Handshake:     time.Duration(connectionConfig.Handshake),
+			MeanLifetime:  time.Duration(connectionConfig.MeanLifetime),
+			Random:        b.random,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown connection behavior %s", connectionConfig.Type)
+	}
+}
+
+func (b *builder) transmissionBehavior(transmissionConfig *TransmissionConfig) (simulator.TransmissionBehavior, error) {
+	if transmissionConfig == nil {
+		return nil, errors.New("no transmission behavior is given")
+	}
+
+	switch transmissionConfig.Type {
+	case "random_drops":
+		return transmission_behavior.RandomDrops{
+			DropChance: transmissionConfig.DropChance,
+			Delay:      time.Duration(transmissionConfig.Delay),
+			Random:     b.random,
+		}, nil
+	case "long_tail":
+		return transmission_behavior.LongTailTransmission{
+			Alpha:      transmissionConfig.Alpha,
+			X_m:        transmissionConfig.XM,
+			DropChance: transmissionConfig.DropChance,
+			Random:     *b.random,
+		}, nil
+	case "delay":
+		return transmission_behavior.DelayTransmission{
+			Delay: time.Duration(transmissionConfig.Delay),
+		}, nil
+	case "ble":
+		return transmission_behavior.BleTransmission{}, nil
+	case "no_drops":
+		return transmission_behavior.NoDrops{}, nil
+	default:
+		return nil, fmt.Errorf("unknown transmission behavior %s", transmissionConfig.Type)
+	}
+}
+
+func (b *builder) movement(movementConfig *MovementConfig) (simulator.NodeMovement, error) {
+	if movementConfig == nil {
+		return nil, errors.New("no movement profile is given")
+	}
+
+	switch movementConfig.Type {
+	case "random":
+		return movement_profiles.NewRandomNode(movementConfig.Width, movementConfig.Height, movementConfig.MaxTime, movementConfig.MinTime, b.random), nil
+	case "random_waypoint":
+		return movement_profiles.NewRandomWaypointNode(movementConfig.Width, movementConfig.Height, movementConfig.MaxSpeed, time.Duration(movementConfig.PauseTime), b.random), nil
+	case "linear":
+		if movementConfig.From == nil || movementConfig.To == nil {
+			return nil, errors.New("linear movement needs from and to")
+		}
+		from, err := b.coordinate(movementConfig.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := b.coordinate(movementConfig.To)
+		if err != nil {
+			return nil, err
+		}
+		return movement_profiles.NewLinearNode(from, to, movementConfig.Seconds), nil
+	case "stationary":
+		if movementConfig.From == nil {
+			return nil, errors.New("stationary movement needs from")
+		}
+		from, err := b.coordinate(movementConfig.From)
+		if err != nil {
+			return nil, err
+		}
+		return &movement_profiles.StationaryNode{StartCoords: from}, nil
+	case "waypoint":
+		if len(movementConfig.Points) == 0 {
+			return nil, errors.New("waypoint movement needs points")
+		}
+		waypoints := movement_profiles.NewWaypointNode()
+		for _, point := range movementConfig.Points {
+			coords, err := b.coordinate(&point.Point)
+			if err != nil {
+				return nil, err
+			}
+			waypoints.AddPoint(simulator.MovementInstruction{
+				Coords: coords,
+				Time:   time.Duration(point.Time),
+			})
+		}
+		return waypoints, nil
+	default:
+		return nil, fmt.Errorf("unknown movement profile %s", movementConfig.Type)
+	}
+}
+
+func (b *builder) coordinate(p *Point) (simulator.Coordinate, error) {
+	if p.Latitude == nil && p.Longitude == nil {
+		return simulator.Coordinate{X: p.X, Y: p.Y, Z: p.Z, Floor: p.Floor}, nil
+	}
+	if p.Latitude == nil || p.Longitude == nil {
+		return simulator.Coordinate{}, errors.New("a point needs both latitude and longitude")
+	}
+	if b.projection == nil {
+		return simulator.Coordinate{}, errors.New("latitude and longitude need a projection")
+	}
+	coords := b.projection.Project(simulator.GeoCoordinate{Latitude: *p.Latitude, Longitude: *p.Longitude, Altitude: p.Z})
+	coords.Floor = p.Floor
+	return coords, nil
+}
+
+func newProjection(projectionConfig *ProjectionConfig) (simulator.Projection, error) {
+	switch projectionConfig.Type {
+	case "equirectangular":
+		if projectionConfig.Origin == nil {
+			return nil, errors.New("equirectangular projection needs an origin")
+		}
+		return simulator.Equirectangular{Origin: projectionConfig.Origin.geoCoordinate()}, nil
+	case "utm":
+		if projectionConfig.Zone != 0 {
+			if projectionConfig.Zone < 1 || projectionConfig.Zone > 60 {
+				return nil, fmt.Errorf("utm zone %d is not between 1 and 60", projectionConfig.Zone)
+			}
+			return simulator.UTM{Zone: projectionConfig.Zone, South: projectionConfig.South}, nil
+		}
+		if projectionConfig.Origin == nil {
+			return nil, errors.New("utm projection needs a zone or an origin")
+		}
+		return simulator.NewUTM(projectionConfig.Origin.geoCoordinate()), nil
+	default:
+		return nil, fmt.Errorf("unknown projection %s", projectionConfig.Type)
+	}
+}
+
+func (g *GeoPoint) geoCoordinate() simulator.GeoCoordinate {
+	return simulator.GeoCoordinate{Latitude: g.Latitude, Longitude: g.Longitude}
+}
+
+func (b *builder) newLogger(loggerConfig LoggerConfig) (simulator.Logger, error) {
+	switch loggerConfig.Type {
+	case "standard":
+		return loggers.NewStandardLogger(), nil
+	case "statistics":
+		return loggers.NewStatisticsLogger(), nil
+	case "energy":
+		return loggers.NewEnergyLogger(), nil
+	case "pcap":
+		return loggers.NewPCAPLogger(), nil
+	case "profile":
+		return loggers.NewProfileLogger(), nil
+	case "record":
+		if loggerConfig.Path == "" {
+			return nil, errors.New("record logger needs a path")
+		}
+		return loggers.NewRecordLogger(loggerConfig.Path), nil
+	case "trajectory":
+		if loggerConfig.Path == "" {
+			return nil, errors.New("trajectory logger needs a path")
+		}
+		if loggerConfig.Interval <= 0 {
+			return nil, errors.New("trajectory logger needs a positive interval")
+		}
+		return loggers.NewTrajectoryLogger(loggerConfig.Path, time.Duration(loggerConfig.Interval), b.projection), nil
+	default:
+		return nil, fmt.Errorf("unknown logger %s", loggerConfig.Type)
+	}
+}
+
+// scenarioScope contains the named scenarios of a single node
+type scenarioScope struct {
+	sessions map[string]*node.ScenarioSession
+	sends    map[string]*node.ScenarioSendData
+}
+
+// scenario builds a scenario tree. Only scenarios added to the node and the children of event scenarios receive callbacks from the node,
+// so scenarios which depend on callbacks cannot be started by delay, multi and count scenarios.
+func (b *builder) scenario(scenarioConfig ScenarioConfig, scope *scenarioScope, receivesCallbacks bool) (node.Scenario, error) {
+	switch scenarioConfig.Type {
+	case "session", "event":
+		if !receivesCallbacks {
+			return nil, fmt.Errorf("%s scenario must be given at the top level or started by an event scenario", scenarioConfig.Type)
+		}
+	}
+
+	children := func() ([]node.Scenario, error) {
+		scenarios := []node.Scenario{}
+		for _, childConfig := range scenarioConfig.Scenarios {
+			child, err := b.scenario(childConfig, scope, scenarioConfig.Type == "event")
+			if err != nil {
+				return nil, err
+			}
+			scenarios = append(scenarios, child)
+		}
+		return scenarios, nil
+	}
+
+	switch scenarioConfig.Type {
+	case "session":
+		orchestrator, found := b.sessions[scenarioConfig.Session]
+		if !found {
+			return nil, fmt.Errorf("session scenario refers to unknown session %s", scenarioConfig.Session)
+		}
+		maintain := true
+		if scenarioConfig.Maintain != nil {
+			maintain = *scenarioConfig.Maintain
+		}
+		session := orchestrator.SessionScenario(maintain)
+		if scenarioConfig.Name != "" {
+			scope.sessions[scenarioConfig.Name] = session
+		}
+		return session, nil
+	case "send":
+		session, found := scope.sessions[scenarioConfig.Session]
+		if !found {
+			return nil, fmt.Errorf("send scenario refers to unknown session scenario %s", scenarioConfig.Session)
+		}
+		send := session.SendDataScenario(scenarioConfig.Data)
+		b.simulation.Sends = append(b.simulation.Sends, send)
+		if scenarioConfig.Name != "" {
+			scope.sends[scenarioConfig.Name] = send
+		}
+		return send, nil
+	case "delay":
+		scenarios, err := children()
+		if err != nil {
+			return nil, err
+		}
+		return node.DelayScenario(node.MultiScenario(scenarios...), time.Duration(scenarioConfig.Delay)), nil
+	case "event":
+		if scenarioConfig.Event == nil {
+			return nil, errors.New("event scenario needs an event")
+		}
+		event, err := b.event(*scenarioConfig.Event, scope)
+		if err != nil {
+			return nil, err
+		}
+		scenarios, err := children()
+		if err != nil {
+			return nil, err
+		}
+		eventScenario := node.EventScenario(event)
+		for _, scenario := range scenarios {
+			eventScenario.OnEvent(scenario)
+		}
+		return eventScenario, nil
+	case "multi":
+		scenarios, err := children()
+		if err != nil {
+			return nil, err
+		}
+		return node.MultiScenario(scenarios...), nil
+	case "count":
+		scenarios, err := children()
+		if err != nil {
+			return nil, err
+		}
+		return node.CountScenario(scenarioConfig.Count, node.MultiScenario(scenarios...)), nil
+	case "terminate":
+		return node.TerminateScenario(), nil
+	case "broadcast_route_request":
+		return node.BroadcastRouteRequestScenario(time.Duration(scenarioConfig.Delay)), nil
+	case "spam_route_requests":
+		return node.SpamRouteRequestScenario(time.Duration(scenarioConfig.Delay)), nil
+	default:
+		return nil, fmt.Errorf("unknown scenario %s", scenarioConfig.Type)
+	}
+}
+
+func (b *builder) event(eventConfig EventConfig, scope *scenarioScope) (node.Event, error) {
+	switch eventConfig.Type {
+	case "connect", "disconnect":
+		n, found := b.nodes[eventConfig.Node]
+		if !found {
+			return node.Event{}, fmt.Errorf("%s event refers to unknown node %s", eventConfig.Type, eventConfig.Node)
+		}
+		if eventConfig.Type == "connect" {
+			return node.ConnectEvent(n.ID()), nil
+		}
+		return node.DisconnectEvent(n.ID()), nil
+	case "log":
+		return node.LogEvent(eventConfig.Prefix), nil
+	case "session_established":
+		contact, found := b.contacts[eventConfig.Contact]
+		if !found {
+			return node.Event{}, fmt.Errorf("session_established event refers to unknown contact %s", eventConfig.Contact)
+		}
+		return node.SessionEstablishedEvent(contact), nil
+	case "receive_data":
+		session, found := scope.sessions[eventConfig.Session]
+		if !found {
+			return node.Event{}, fmt.Errorf("receive_data event refers to unknown session scenario %s", eventConfig.Session)
+		}
+		return session.ReceiveDataEvent(eventConfig.Data), nil
+	case "delivered":
+		send, found := scope.sends[eventConfig.Send]
+		if !found {
+			return node.Event{}, fmt.Errorf("delivered event refers to unknown send scenario %s", eventConfig.Send)
+		}
+		return send.DeliveredEvent(), nil
+	case "terminate":
+		return node.TerminateEvent(), nil
+	default:
+		return node.Event{}, fmt.Errorf("unknown event %s", eventConfig.Type)
+	}
+}
@@ -0,0 +1,423 @@
+package scenario_config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a complete simulation, it can be read from a JSON or YAML file
+type Config struct {
+	// Seed of the shared random source, a random seed is used if it is not given
+	Seed *int64 `json:"seed" yaml:"seed"`
+	// BLERange is the range within which nodes connect
+	BLERange float64 `json:"bleRange" yaml:"bleRange"`
+	// TransmissionDelay is passed to the simulator as the default delay of transmissions
+	TransmissionDelay Duration `json:"transmissionDelay" yaml:"transmissionDelay"`
+	// Duration is the simulated time to run for, if it is not given the simulation is shown in the visualizer
+	Duration Duration `json:"duration" yaml:"duration"`
+	// Timeout is the wall-clock time after which the run is stopped
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+	// Speed paces the run so the simulated time advances Speed times as fast as the wall clock, and is the initial speed of the visualizer
+	Speed float64 `json:"speed" yaml:"speed"`
+	// StallThreshold is the lag behind the wall clock after which a paced run reports a stall
+	StallThreshold Duration `json:"stallThreshold" yaml:"stallThreshold"`
+	// MaxEvents is the number of events after which the run is stopped
+	MaxEvents int `json:"maxEvents" yaml:"maxEvents"`
+	// StopWhenDelivered stops the run once the data of all send scenarios has been delivered
+	StopWhenDelivered bool `json:"stopWhenDelivered" yaml:"stopWhenDelivered"`
+	// Topography is the path of a Vadere scenario file, whose obstacles are shown in the visualizer
+	Topography string `json:"topography" yaml:"topography"`
+	// Obstacles makes the obstacles of the topography affect connectivity
+	Obstacles *ObstaclesConfig `json:"obstacles" yaml:"obstacles"`
+	// Projection allows positions to be given as latitude and longitude
+	Projection *ProjectionConfig `json:"projection" yaml:"projection"`
+
+	Simulator    *SimulatorConfig    `json:"simulator" yaml:"simulator"`
+	Protocol     *ProtocolConfig     `json:"protocol" yaml:"protocol"`
+	Movement     *MovementConfig     `json:"movement" yaml:"movement"`
+	Transmission *TransmissionConfig `json:"transmission" yaml:"transmission"`
+	Buffer       *BufferConfig       `json:"buffer" yaml:"buffer"`
+	Bandwidth    *BandwidthConfig    `json:"bandwidth" yaml:"bandwidth"`
+	Energy       *EnergyConfig       `json:"energy" yaml:"energy"`
+	Discovery    *DiscoveryConfig    `json:"discovery" yaml:"discovery"`
+	Connection   *ConnectionConfig   `json:"connection" yaml:"connection"`
+	Loggers      []LoggerConfig      `json:"loggers" yaml:"loggers"`
+	Nodes        []NodeConfig        `json:"nodes" yaml:"nodes"`
+	Contacts     []ContactConfig     `json:"contacts" yaml:"contacts"`
+	Sessions     []SessionConfig     `json:"sessions" yaml:"sessions"`
+	Partitions   []PartitionConfig   `json:"partitions" yaml:"partitions"`
+}
+
+type ObstaclesConfig struct {
+	// Attenuation is the loss in dB of links crossing an obstacle, the obstacles block links if it is 0
+	Attenuation float64 `json:"attenuation" yaml:"attenuation"`
+}
+
+// ProjectionConfig selects the projection of geographic coordinates
+type ProjectionConfig struct {
+	// Type is one of equirectangular and utm
+	Type string `json:"type" yaml:"type"`
+	// Origin is placed at 0, 0 by equirectangular, and selects the zone of utm if Zone is not given
+	Origin *GeoPoint `json:"origin" yaml:"origin"`
+	Zone   int       `json:"zone" yaml:"zone"`
+	South  bool      `json:"south" yaml:"south"`
+}
+
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude" yaml:"latitude"`
+	Longitude float64 `json:"longitude" yaml:"longitude"`
+}
+
+// SimulatorConfig overrides fields of simulator.SimulatorOptions
+type SimulatorConfig struct {
+	TimeStep          Duration `json:"timeStep" yaml:"timeStep"`
+	ContactPrediction bool     `json:"contactPrediction" yaml:"contactPrediction"`
+	// FloorAttenuation is the loss in dB of each floor slab between two nodes
+	FloorAttenuation *float64 `json:"floorAttenuation" yaml:"floorAttenuation"`
+}
+
+// ProtocolConfig overrides fields of device.ProtocolOptions
+type ProtocolConfig struct {
+	EnableSync                  *bool     `json:"enableSync" yaml:"enableSync"`
+	DisableAutoRREQOnConnection *bool     `json:"disableAutoRREQOnConnection" yaml:"disableAutoRREQOnConnection"`
+	MaxRREQTTL                  *int      `json:"maxRREQTTL" yaml:"maxRREQTTL"`
+	RREQBroadcastStrategy       string    `json:"rreqBroadcastStrategy" yaml:"rreqBroadcastStrategy"` // One of all, log and two
+	ForwardRREQsWhenMatching    *bool     `json:"forwardRREQsWhenMatching" yaml:"forwardRREQsWhenMatching"`
+	ACKDelay                    *Duration `json:"ackDelay" yaml:"ackDelay"`
+	ACKTimeout                  *Duration `json:"ackTimeout" yaml:"ackTimeout"`
+}
+
+// MovementConfig selects a movement profile from movement_profiles
+type MovementConfig struct {
+	// Type is one of random, random_waypoint, linear, waypoint and stationary
+	Type string `json:"type" yaml:"type"`
+
+	// random and random_waypoint
+	Width    int `json:"width" yaml:"width"`
+	Height   int `json:"height" yaml:"height"`
+	MaxTime  int `json:"maxTime" yaml:"maxTime"`
+	MinTime  int `json:"minTime" yaml:"minTime"`
+	MaxSpeed int `json:"maxSpeed" yaml:"maxSpeed"`
+	// PauseTime is the pause between waypoints of random_waypoint
+	PauseTime Duration `json:"pauseTime" yaml:"pauseTime"`
+
+	// linear and stationary
+	From    *Point `json:"from" yaml:"from"`
+	To      *Point `json:"to" yaml:"to"`
+	Seconds int    `json:"seconds" yaml:"seconds"`
+
+	// waypoint
+	Points []WaypointConfig `json:"points" yaml:"points"`
+}
+
+// Point is a position, z and floor are only needed in multi-floor venues.
+// If latitude and longitude are given, they are projected using the projection of the config instead of x and y.
+type Point struct {
+	X         float64  `json:"x" yaml:"x"`
+	Y         float64  `json:"y" yaml:"y"`
+	Z         float64  `json:"z" yaml:"z"`
+	Floor     int      `json:"floor" yaml:"floor"`
+	Latitude  *float64 `json:"latitude" yaml:"latitude"`
+	Longitude *float64 `json:"longitude" yaml:"longitude"`
+}
+
+type WaypointConfig struct {
+	Point `yaml:",inline"`
+	Time  Duration `json:"time" yaml:"time"`
+}
+
+// TransmissionConfig selects a transmission behavior from transmission_behavior
+type TransmissionConfig struct {
+	// Type is one of random_drops, long_tail, delay, ble and no_drops
+	Type       string   `json:"type" yaml:"type"`
+	DropChance float64  `json:"dropChance" yaml:"dropChance"`
+	Delay      Duration `json:"delay" yaml:"delay"`
+	Alpha      float64  `json:"alpha" yaml:"alpha"`
+	XM         float64  `json:"xm" yaml:"xm"`
+}
+
+// BufferConfig sets the send buffer of nodes
+type BufferConfig struct {
+	Size int `json:"size" yaml:"size"`
+	// Discipline is one of drop_tail, drop_head, random_early_drop and priority
+	Discipline string `json:"discipline" yaml:"discipline"`
+
+	// random_early_drop
+	MinThreshold   float64 `json:"minThreshold" yaml:"minThreshold"`
+	MaxThreshold   float64 `json:"maxThreshold" yaml:"maxThreshold"`
+	MaxProbability float64 `json:"maxProbability" yaml:"maxProbability"`
+}
+
+// BandwidthConfig selects a bandwidth model, which makes the transmission time depend on the packet size
+type BandwidthConfig struct {
+	// Type is one of fixed and ble
+	Type string `json:"type" yaml:"type"`
+	// BitsPerSecond is the data rate of fixed
+	BitsPerSecond float64 `json:"bitsPerSecond" yaml:"bitsPerSecond"`
+}
+
+// DiscoveryConfig selects a discovery model, which delays connections between nodes that come within range
+type DiscoveryConfig struct {
+	// Type is one of fixed and ble
+	Type string `json:"type" yaml:"type"`
+	// Delay of fixed
+	Delay Duration `json:"delay" yaml:"delay"`
+
+	// ble, the defaults of simulator.DefaultBleDiscovery are used for fields that are not given
+	AdvertisingInterval Duration `json:"advertisingInterval" yaml:"advertisingInterval"`
+	ScanInterval        Duration `json:"scanInterval" yaml:"scanInterval"`
+	ScanWindow          Duration `json:"scanWindow" yaml:"scanWindow"`
+	ConnectionSetup     Duration `json:"connectionSetup" yaml:"connectionSetup"`
+}
+
+// ConnectionConfig selects a connection behavior from connection_behavior
+type ConnectionConfig struct {
+	// Type is one of reliable and random_failures
+	Type          string   `json:"type" yaml:"type"`
+	Handshake     Duration `json:"handshake" yaml:"handshake"`
+	FailureChance float64  `json:"failureChance" yaml:"failureChance"`
+	MeanLifetime  Duration `json:"meanLifetime" yaml:"meanLifetime"`
+}
+
+type LoggerConfig struct {
+	// Type is one of standard, statistics, energy, pcap, profile, record and trajectory
+	Type string `json:"type" yaml:"type"`
+	// Path of the event log written by the record logger, or the CSV file written by the trajectory logger
+	Path string `json:"path" yaml:"path"`
+	// Interval between the positions written by the trajectory logger
+	Interval Duration `json:"interval" yaml:"interval"`
+}
+
+// NodeConfig describes a single node, or a group of Count identical nodes
+type NodeConfig struct {
+	// Name is used to refer to the node. The nodes of a group are named name[0], name[1], ...,
+	// and the name of the group refers to all of them.
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+	// ID of the node, or of the first node in the group. Nodes are numbered sequentially if it is not given.
+	ID *int64 `json:"id" yaml:"id"`
+	// Delay before the nodes are added to the simulation
+	Delay Duration `json:"delay" yaml:"delay"`
+	// RemoveAt is the time at which the nodes are removed from the simulation
+	RemoveAt *Duration `json:"removeAt" yaml:"removeAt"`
+	// CrashAt is the time at which the nodes crash, they restart after RestartAfter
+	CrashAt      *Duration `json:"crashAt" yaml:"crashAt"`
+	RestartAfter Duration  `json:"restartAfter" yaml:"restartAfter"`
+	// Crashes makes the nodes crash at random
+	Crashes *CrashesConfig `json:"crashes" yaml:"crashes"`
+	// PersistState makes the nodes keep their synchronization states when they restart after a crash
+	PersistState bool `json:"persistState" yaml:"persistState"`
+	// Colors enables the network layer colors in the visualizer
+	Colors bool `json:"colors" yaml:"colors"`
+
+	Movement     *MovementConfig     `json:"movement" yaml:"movement"`
+	Transmission *TransmissionConfig `json:"transmission" yaml:"transmission"`
+	Protocol     *ProtocolConfig     `json:"protocol" yaml:"protocol"`
+	Buffer       *BufferConfig       `json:"buffer" yaml:"buffer"`
+	Bandwidth    *BandwidthConfig    `json:"bandwidth" yaml:"bandwidth"`
+	Energy       *EnergyConfig       `json:"energy" yaml:"energy"`
+	Clock        *ClockConfig        `json:"clock" yaml:"clock"`
+	// TransmitPowerOffset and ReceiverSensitivityOffset in dB change the range of the nodes, see simulator.NodeOptions
+	TransmitPowerOffset       float64 `json:"transmitPowerOffset" yaml:"transmitPowerOffset"`
+	ReceiverSensitivityOffset float64 `json:"receiverSensitivityOffset" yaml:"receiverSensitivityOffset"`
+	// MaxConnections limits the number of concurrent connections of the nodes
+	MaxConnections int `json:"maxConnections" yaml:"maxConnections"`
+	// PeerSelection is one of keep, nearest, random, oldest and protocol, it decides which nodes to connect to when there are no free connection slots
+	PeerSelection string `json:"peerSelection" yaml:"peerSelection"`
+	// Vadere creates a node for each trajectory of a Vadere output file, instead of Count nodes
+	Vadere *VadereConfig `json:"vadere" yaml:"vadere"`
+
+	// Scenarios are started on each of the nodes
+	Scenarios []ScenarioConfig `json:"scenarios" yaml:"scenarios"`
+}
+
+// CrashesConfig crashes the nodes after exponentially distributed uptimes, and restarts them after exponentially distributed downtimes
+type CrashesConfig struct {
+	MeanUptime   Duration `json:"meanUptime" yaml:"meanUptime"`
+	MeanDowntime Duration `json:"meanDowntime" yaml:"meanDowntime"`
+}
+
+// EnergyConfig gives the energy profile of the nodes, see simulator.EnergyProfile.
+// The values of the profile override those of the named device profile.
+type EnergyConfig struct {
+	// Profile is one of smartphone and wearable, or empty to start from a profile without costs
+	Profile         string   `json:"profile" yaml:"profile"`
+	Capacity        *float64 `json:"capacity" yaml:"capacity"`
+	TransmitPerByte *float64 `json:"transmitPerByte" yaml:"transmitPerByte"`
+	ReceivePerByte  *float64 `json:"receivePerByte" yaml:"receivePerByte"`
+	ConnectedPower  *float64 `json:"connectedPower" yaml:"connectedPower"`
+	ScanPower       *float64 `json:"scanPower" yaml:"scanPower"`
+}
+
+// ClockConfig gives the nodes clocks that are not synchronized, see simulator.Clock.
+// Each node gets a random offset and drift within the given spread around Offset and Drift.
+type ClockConfig struct {
+	Offset       Duration `json:"offset" yaml:"offset"`
+	OffsetSpread Duration `json:"offsetSpread" yaml:"offsetSpread"`
+	// Drift is given in parts per million, e.g. 50 for a clock that gains 50 µs every second
+	Drift       float64 `json:"drift" yaml:"drift"`
+	DriftSpread float64 `json:"driftSpread" yaml:"driftSpread"`
+}
+
+type VadereConfig struct {
+	Path     string  `json:"path" yaml:"path"`
+	KeepRate float64 `json:"keepRate" yaml:"keepRate"`
+}
+
+// ContactConfig links the given nodes, using LinkNodes for two nodes and GroupLinkNodes otherwise
+type ContactConfig struct {
+	Name  string   `json:"name" yaml:"name"`
+	Nodes []string `json:"nodes" yaml:"nodes"`
+}
+
+// PartitionConfig schedules a simulator.Partition, which separates the groups of nodes or the sides of the cuts from start until heal
+type PartitionConfig struct {
+	Start Duration `json:"start" yaml:"start"`
+	Heal  Duration `json:"heal" yaml:"heal"`
+	// Groups contains the names of the nodes in each group
+	Groups [][]string  `json:"groups" yaml:"groups"`
+	Cuts   []CutConfig `json:"cuts" yaml:"cuts"`
+}
+
+type CutConfig struct {
+	From Point `json:"from" yaml:"from"`
+	To   Point `json:"to" yaml:"to"`
+}
+
+// SessionConfig creates a SessionOrchestrator for a contact
+type SessionConfig struct {
+	Name    string `json:"name" yaml:"name"`
+	Contact string `json:"contact" yaml:"contact"`
+}
+
+// ScenarioConfig describes a tree of starling_node scenarios
+type ScenarioConfig struct {
+	// Type is one of session, send, delay, event, multi, count, terminate, broadcast_route_request and spam_route_requests
+	Type string `json:"type" yaml:"type"`
+	// Name is used by events to refer to session and send scenarios of the same node
+	Name string `json:"name" yaml:"name"`
+
+	// Session is the session orchestrator of a session scenario, or the session scenario of a send scenario
+	Session  string `json:"session" yaml:"session"`
+	Maintain *bool  `json:"maintain" yaml:"maintain"`
+	Data     string `json:"data" yaml:"data"`
+
+	Delay Duration     `json:"delay" yaml:"delay"`
+	Count int          `json:"count" yaml:"count"`
+	Event *EventConfig `json:"event" yaml:"event"`
+
+	// Scenarios are started by delay, event, multi and count scenarios
+	Scenarios []ScenarioConfig `json:"scenarios" yaml:"scenarios"`
+}
+
+// EventConfig describes a starling_node event, which triggers an event scenario
+type EventConfig struct {
+	// Type is one of connect, disconnect, log, session_established, receive_data, delivered and terminate
+	Type string `json:"type" yaml:"type"`
+	// Node of connect and disconnect events
+	Node string `json:"node" yaml:"node"`
+	// Prefix of log events
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// Contact of session_established events
+	Contact string `json:"contact" yaml:"contact"`
+	// Session scenario of receive_data events
+	Session string `json:"session" yaml:"session"`
+	Data    string `json:"data" yaml:"data"`
+	// Send scenario of delivered events
+	Send string `json:"send" yaml:"send"`
+}
+
+// Duration is a time.Duration which is written as a string such as "1m30s", or as a number of seconds
+type Duration time.Duration
+
+func (d *Duration) parse(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(duration)
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case int:
+		*d = Duration(time.Duration(v) * time.Second)
+	default:
+		return fmt.Errorf("invalid duration %v", value)
+	}
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+type Format int
+
+const (
+	JSON Format = iota
+	YAML
+)
+
+// Load reads a config file, the format is determined by the file extension
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := JSON
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = YAML
+	}
+
+	config, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Parse decodes a config, unknown fields are reported as errors
+func Parse(data []byte, format Format) (*Config, error) {
+	config := &Config{}
+
+	switch format {
+	case JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	case YAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	default:
+		panic("unknown config format")
+	}
+
+	return config, nil
+}
@@ -5,18 +5,35 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"time"
+
+	"github.com/starling-protocol/starling/utils"
 )
 
+// A Coordinate is a position in the simulated area.
+// Z is the height above the ground, and Floor is the index of the storey the position is on in multi-floor venues.
+// Both are zero for flat scenarios.
 type Coordinate struct {
-	X float64
-	Y float64
+	X     float64
+	Y     float64
+	Z     float64
+	Floor int
 }
 
 func (a *Coordinate) Distance(b Coordinate) float64 {
 	dX := a.X - b.X
 	dY := a.Y - b.Y
-	return math.Sqrt(dX*dX + dY*dY)
+	dZ := a.Z - b.Z
+	return math.Sqrt(dX*dX + dY*dY + dZ*dZ)
+}
+
+// FloorsBetween returns the number of floor slabs separating a and b
+func (a *Coordinate) FloorsBetween(b Coordinate) int {
+	if a.Floor > b.Floor {
+		return a.Floor - b.Floor
+	}
+	return b.Floor - a.Floor
 }
 
 type MovementInstruction struct {
@@ -31,6 +48,15 @@ func NewMovementInstruction(x float64, y float64, time time.Duration) MovementIn
 	}
 }
 
+// NewFloorMovementInstruction returns an instruction to move to the given position on the given floor.
+// The node changes floor when it arrives.
+func NewFloorMovementInstruction(x float64, y float64, z float64, floor int, time time.Duration) MovementInstruction {
+	return MovementInstruction{
+		Coords: Coordinate{X: x, Y: y, Z: z, Floor: floor},
+		Time:   time,
+	}
+}
+
 type NodeMovement interface {
 	StartPosition() Coordinate
 	RegisterMovements(Coordinate) MovementInstruction
@@ -40,6 +66,21 @@ type TransmissionBehavior interface {
 	Transmission(Coordinate, Coordinate, []byte) (shouldBeDropped bool, delay time.Duration)
 }
 
+// A FloorTransmissionBehavior is a TransmissionBehavior which accounts for the floor slabs between the nodes.
+// The simulator calls TransmissionThroughFloors instead of Transmission, with the loss in dB of the slabs
+// given by the floor attenuation of its options.
+type FloorTransmissionBehavior interface {
+	TransmissionBehavior
+	TransmissionThroughFloors(originCoord Coordinate, targetCoord Coordinate, floorLoss float64, packet []byte) (shouldBeDropped bool, delay time.Duration)
+}
+
+// A ConnectionBehavior decides the outcome of an attempt to connect two nodes within range.
+// It returns whether the attempt fails, how long the handshake takes, and how long the connection lasts
+// before it drops by itself. A non-positive lifetime means that the connection lasts until the nodes move apart.
+type ConnectionBehavior interface {
+	Connection(Coordinate, Coordinate) (shouldFail bool, handshake time.Duration, lifetime time.Duration)
+}
+
 type NodeID int64
 
 type Node interface {
@@ -60,25 +101,91 @@ type Peer struct {
 func (p Peer) SendPacket(packet []byte) {
 	sim := p.origin.sim
 
-	if p.origin.curBufferCount+1 > p.origin.bufferSize {
+	// The packet at the head of the buffer is scheduled for transmission, so it is not offered to the queue discipline
+	queued := [][]byte{}
+	if len(p.origin.queue) > 1 {
+		queued = make([][]byte, len(p.origin.queue)-1)
+		for i, queuedEvent := range p.origin.queue[1:] {
+			queued[i] = queuedEvent.packet
+		}
+	}
+	accept, drop := p.origin.queueDiscipline.Admit(queued, packet, p.origin.curBufferCount, p.origin.bufferSize, p.origin.random)
+	if drop >= 0 {
+		// The waiting packets have not been scheduled, so dropping one frees its airtime for the packets behind it
+		dropped := p.origin.queue[drop+1]
+		p.origin.dequeue(dropped)
+		p.origin.curBufferCount--
+		sim.logDebug(fmt.Sprintf("simulator:packet:queue_drop:%d:%d", p.origin.nodeID, dropped.targetNodeID))
+		sim.dropFromBuffer(p.origin, dropped.targetNodeID, dropped.packet, true)
+	}
+	if !accept {
 		sim.logDebug(fmt.Sprintf("simulator:packet:buffer_full:%d:%d", p.origin.nodeID, p.target.nodeID))
+		sim.dropFromBuffer(p.origin, p.target.nodeID, packet, false)
 		return
 	}
 
-	transmissionBehavior := p.origin.node.TransmissionBehavior()
-	shouldBeDropped, propagationDelay := transmissionBehavior.Transmission(p.origin.coords, p.target.coords, packet)
+	if sim.options.ContactPrediction {
+		sim.refreshPosition(p.origin)
+		sim.refreshPosition(p.target)
+	}
+
+	shouldBeDropped, propagationDelay := sim.transmission(p.origin.node.TransmissionBehavior(), p.origin.coords, p.target.coords, packet)
 
 	p.origin.curBufferCount++
 
+	sendEvent := sim.newSendEvent(p.target, p.origin, p.target.nodeID, p, shouldBeDropped, propagationDelay, packet)
+	p.origin.queue = append(p.origin.queue, sendEvent)
+	if len(p.origin.queue) == 1 {
+		sim.scheduleSend(p.origin)
+	}
+}
+
+// scheduleSend schedules the transmission of the packet at the head of the buffer of the node.
+// The packets behind it wait in the buffer without occupying the radio, until they reach the head.
+func (s *Simulator) scheduleSend(node *InternalNode) {
+	sendEvent := node.queue[0]
+	target := sendEvent.target
+
 	var sendTime time.Duration
-	if p.origin.lastMessageSent < sim.time {
-		sendTime = sim.time + sim.transmissionDelay
+	if bandwidth := node.bandwidth; bandwidth != nil {
+		if s.options.ContactPrediction {
+			s.refreshPosition(node)
+			s.refreshPosition(target)
+		}
+		// The packet is transmitted once both radios are free, and occupies them while it is serialized
+		start := max(s.time, node.lastMessageSent, target.lastMessageReceived)
+		rate := s.dataRate(bandwidth, node.coords, target.coords)
+		sendTime = start + s.transmissionDelay + serializationTime(len(sendEvent.packet), rate)
+		target.lastMessageReceived = sendTime
 	} else {
-		sendTime = p.origin.lastMessageSent + sim.transmissionDelay
+		sendTime = max(s.time, node.lastMessageSent) + s.transmissionDelay
 	}
 
-	sim.pushSendEvent(sendTime, p.target, p.origin, p.target.nodeID, p, shouldBeDropped, propagationDelay, packet)
-	p.origin.lastMessageSent = sendTime + propagationDelay // This represents the time at which the next message could begin sending
+	sendEvent.time = sendTime
+	heap.Push(s.eventQueue, sendEvent)
+	node.lastMessageSent = sendTime + sendEvent.delay // This represents the time at which the next message could begin sending
+}
+
+// dropQueuedPackets drops the packets in the buffers of all nodes that are sent by or to the node,
+// and schedules the next packet of the buffers whose head was dropped
+func (s *Simulator) dropQueuedPackets(iNode *InternalNode) {
+	for _, node := range s.nodes {
+		if len(node.queue) == 0 {
+			continue
+		}
+		head := node.queue[0]
+		node.queue = slices.DeleteFunc(node.queue, func(sendEvent *SendEvent) bool {
+			if sendEvent.origin != iNode && sendEvent.target != iNode {
+				return false
+			}
+			node.curBufferCount--
+			sendEvent.cancelled = true
+			return true
+		})
+		if len(node.queue) > 0 && node.queue[0] != head {
+			s.scheduleSend(node)
+		}
+	}
 }
 
 func (p *Peer) Data() *map[string]any {
@@ -94,6 +201,13 @@ type Logger interface {
 	Init()
 }
 
+// A FlushingLogger is a Logger which buffers its output. It is flushed when Run returns,
+// such that the output is complete when the simulation stops without terminating.
+type FlushingLogger interface {
+	Logger
+	Flush() error
+}
+
 type InternalPeer struct {
 	a     *InternalNode
 	b     *InternalNode
@@ -120,40 +234,151 @@ func (p *InternalPeer) Data(node *InternalNode) *map[string]any {
 	}
 }
 
+type SimulatorOptions struct {
+	// TimeStep is the interval at which node positions are updated and connections are checked.
+	TimeStep time.Duration
+	// When ContactPrediction is enabled, the simulator computes the exact times at which nodes enter and leave
+	// each other's range from their movement instructions, instead of checking every node at every time step.
+	ContactPrediction bool
+	// Bandwidth is the default bandwidth model of nodes. If it is nil, packets are spaced by the transmission delay regardless of their size.
+	Bandwidth BandwidthModel
+	// Discovery delays connections between nodes that come within range. If it is nil, nodes connect as soon as they are within range.
+	Discovery DiscoveryModel
+	// Connection decides whether connection attempts succeed, and how long the connections last. If it is nil, connections always succeed instantly.
+	// Nodes attempt to connect again after a failed attempt or a dropped connection, as long as they are within range.
+	Connection ConnectionBehavior
+	// Topography contains obstacles which attenuate or block the links crossing them
+	Topography *Topography
+	// FloorAttenuation is the loss in dB of each floor slab between two nodes, an infinite attenuation isolates the floors
+	FloorAttenuation float64
+	// Projection maps geographic coordinates to the coordinates of the simulator, if positions are given as latitude and longitude.
+	Projection Projection
+}
+
+func DefaultSimulatorOptions() *SimulatorOptions {
+	return &SimulatorOptions{
+		TimeStep:          10 * time.Millisecond,
+		ContactPrediction: false,
+		Bandwidth:         nil,
+		Discovery:         nil,
+		Connection:        nil,
+		Topography:        nil,
+		FloorAttenuation:  15,
+		Projection:        nil,
+	}
+}
+
+type NodeOptions struct {
+	// BufferSize is the number of packets the node can hold, while they wait to be transmitted or are in transmission.
+	BufferSize int
+	// QueueDiscipline decides which packets are dropped when the buffer is congested.
+	QueueDiscipline QueueDiscipline
+	// Bandwidth overrides the bandwidth model of the simulator for packets sent by the node, if it is not nil.
+	Bandwidth BandwidthModel
+	// TransmitPowerOffset and ReceiverSensitivityOffset are the transmit power and receiver sensitivity of the node in dB
+	// relative to a typical node, for which the range of the simulator applies. A positive transmit power offset
+	// and a negative sensitivity offset extend the range. Nodes connect when either of them can hear the other,
+	// and packets sent over a link that only works in the other direction are dropped.
+	TransmitPowerOffset       float64
+	ReceiverSensitivityOffset float64
+	// MaxConnections is the number of concurrent connections the node supports, or 0 if it is unlimited.
+	// When the node has no free connection slots, PeerSelection decides whether to evict a peer for a new node within range.
+	MaxConnections int
+	PeerSelection  PeerSelection
+	// Crashes decides when the node crashes by itself, if it is not nil. Crashes can also be scheduled with ScheduleCrash.
+	Crashes CrashModel
+	// PersistState makes the node keep the state it has persisted when it restarts after a crash
+	PersistState bool
+	// Energy enables the energy accounting of the node if it is not nil, see EnergyProfile
+	Energy *EnergyProfile
+	// Clock is the local clock of the node, which is synchronized with the simulated time by default
+	Clock Clock
+}
+
+func DefaultNodeOptions() *NodeOptions {
+	return &NodeOptions{
+		BufferSize:                400,
+		QueueDiscipline:           DropTail{},
+		Bandwidth:                 nil,
+		TransmitPowerOffset:       0,
+		ReceiverSensitivityOffset: 0,
+		MaxConnections:            0,
+		PeerSelection:             KeepPeers{},
+		Crashes:                   nil,
+		PersistState:              false,
+		Energy:                    nil,
+		Clock:                     Clock{},
+	}
+}
+
 type Simulator struct {
+	options               SimulatorOptions
 	eventQueue            *EventQueue
 	nodes                 []*InternalNode
-	peers                 []InternalPeer
+	registeredNodes       []*InternalNode
+	peers                 peerSet
 	isRunning             bool
 	nodeRange             float64 // nodeRange is the actual node range squared (for more efficient distance computation)
 	transmissionDelay     time.Duration
 	time                  time.Duration
 	random                *rand.Rand
+	randomSources         []*RandomSource
 	currentSequenceNumber int64
 	loggers               []Logger
+	interceptors          []Interceptor
 	lastEvent             Event
+	movedNodes            []*InternalNode // movedNodes are the nodes that moved since the last event was passed to the loggers
 	regionMap             *RegionMap
 	isTerminating         bool
-}
+	predictions           map[nodePair][]Event
+	discoveries           map[nodePair]*discovery
+	declined              map[nodePair][2]*InternalNode
+	drops                 map[nodePair]*DisconnectEvent
+	departures            map[nodePair]*DisconnectEvent // departures are the disconnect events of nodes leaving range, without contact prediction
+	partitions            []*scheduledPartition
+	replaying             bool
+	replay                *eventLogReplay
+}
+
+// NewSimulator constructs a new Simulator. If options is nil, DefaultSimulatorOptions is used.
+func NewSimulator(bleRange float64, transmissionDelay time.Duration, random *rand.Rand, loggers []Logger, options *SimulatorOptions) *Simulator {
+	if options == nil {
+		options = DefaultSimulatorOptions()
+	}
+	if options.TimeStep <= 0 {
+		panic("simulator time step must be positive")
+	}
 
-func NewSimulator(bleRange float64, transmissionDelay time.Duration, random *rand.Rand, loggers []Logger) *Simulator {
 	pq := make(EventQueue, 0)
 	heap.Init(&pq)
 
+	regionMap := NewRegionMap(bleRange)
+	regionMap.topography = options.Topography
+	regionMap.floorAttenuation = options.FloorAttenuation
+
 	return &Simulator{
+		options:               *options,
 		eventQueue:            &pq,
 		nodes:                 []*InternalNode{},
-		peers:                 []InternalPeer{},
+		registeredNodes:       []*InternalNode{},
+		peers:                 newPeerSet(),
 		isRunning:             false,
 		nodeRange:             bleRange * bleRange,
 		transmissionDelay:     transmissionDelay,
 		time:                  0,
 		random:                random,
+		randomSources:         []*RandomSource{},
 		currentSequenceNumber: 0,
 		loggers:               loggers,
 		lastEvent:             nil,
-		regionMap:             NewRegionMap(bleRange),
+		movedNodes:            []*InternalNode{},
+		regionMap:             regionMap,
 		isTerminating:         false,
+		predictions:           make(map[nodePair][]Event),
+		discoveries:           make(map[nodePair]*discovery),
+		declined:              make(map[nodePair][2]*InternalNode),
+		drops:                 make(map[nodePair]*DisconnectEvent),
+		departures:            make(map[nodePair]*DisconnectEvent),
 	}
 }
 
@@ -162,6 +387,13 @@ func (s *Simulator) Start() {
 	if !s.isRunning {
 		s.isRunning = true
 
+		if s.replaying {
+			for _, logger := range s.loggers {
+				logger.Init()
+			}
+			return
+		}
+
 		// Push initial event
 		s.pushTimeStepEvent(0)
 		s.lastEvent = (*s.eventQueue)[0]
@@ -174,10 +406,22 @@ func (s *Simulator) Start() {
 		for _, iNode := range s.nodes {
 			iNode.startNode()
 			iNode.nodeID = iNode.node.ID()
+			s.scheduleRandomCrash(iNode)
+			s.startEnergy(iNode)
 		}
 	}
 }
 
+// Projection returns the projection of geographic coordinates, or nil if the simulator does not use one
+func (s *Simulator) Projection() Projection {
+	return s.options.Projection
+}
+
+// Topography returns the obstacles of the simulator, or nil if there are none
+func (s *Simulator) Topography() *Topography {
+	return s.options.Topography
+}
+
 func (s *Simulator) IsRunning() bool {
 	return s.isRunning
 }
@@ -190,89 +434,177 @@ func (s *Simulator) Nodes() []*InternalNode {
 	return s.nodes
 }
 
+func (s *Simulator) Loggers() []Logger {
+	return s.loggers
+}
+
 func (s *Simulator) Peers() []InternalPeer {
-	return s.peers
+	return s.peers.peers
 }
 
+// Now returns the simulated time, while the nodes see the time of their local clocks
 func (s *Simulator) Now() time.Time {
 	return time.Unix(0, 0).Add(s.time)
 }
 
 func (s *Simulator) Update(updateUntilTime time.Duration) error {
+	_, err := s.processEvents(updateUntilTime, nil)
+	return err
+}
+
+// processEvents processes the events until the given time, or until stop returns true after an event has been processed.
+// It returns true if the simulation has terminated.
+func (s *Simulator) processEvents(updateUntilTime time.Duration, stop func() bool) (bool, error) {
 	if !s.isRunning {
 		s.Start()
 	}
 
-	if s.eventQueue.Len() > 0 {
+	if s.replaying {
+		return s.replayUntil(updateUntilTime, stop)
+	}
 
+	positionTime := s.time
+	for s.eventQueue.Len() > 0 {
 		e := heap.Pop(s.eventQueue).(Event)
+		if e.Time() > updateUntilTime {
+			heap.Push(s.eventQueue, e)
+			positionTime = updateUntilTime
+			break
+		}
 
-		for {
-			if e.Time() > updateUntilTime {
-				heap.Push(s.eventQueue, e)
-				break
-			}
+		if e.base().cancelled {
+			continue
+		}
 
-			s.time = e.Time()
-			s.updateLoggers(e)
-			s.lastEvent = e
+		s.time = e.Time()
+		if !s.intercept(e) {
+			continue
+		}
+		if connectEvent, ok := e.(*ConnectEvent); ok && (s.failConnection(connectEvent) || !s.admitConnection(connectEvent)) {
+			continue
+		}
+		s.updateLoggers(e)
+		s.lastEvent = e
 
-			switch e.EventType() {
-			case TIMESTEP:
+		switch e.EventType() {
+		case TIMESTEP:
+			if !s.options.ContactPrediction {
 				s.updateLocations()
-				s.pushTimeStepEvent(e.Time() + 10*time.Millisecond)
-			case CONNECT:
-				connectEvent := e.(*ConnectEvent)
-				s.connectNodes(connectEvent.nodeA, connectEvent.nodeB)
-			case DISCONNECT:
-				disconnectEvent := e.(*DisconnectEvent)
-				s.disconnectNodes(disconnectEvent.nodeA, disconnectEvent.nodeB)
-			case ADD_NODE:
-				addNodeEvent := e.(*AddNodeEvent)
-				iNode := addNodeEvent.node
-				s.nodes = append(s.nodes, iNode)
-				s.regionMap.AddNode(iNode)
-				iNode.startNode()
-				iNode.nodeID = iNode.node.ID()
-			case RCV_MSG:
-				receiveEvent := e.(*ReceiveEvent)
-				receiveEvent.origin.curBufferCount--
-				if receiveEvent.target.hasNeighbour(receiveEvent.origin) {
-					packet := receiveEvent.packet
-					receiveEvent.target.node.OnReceivePacket(receiveEvent.peer, packet, receiveEvent.originNodeID)
-				}
-			case SEND_MSG:
-				sendEvent := e.(*SendEvent)
-				s.sendPacket(sendEvent)
-			case DELAY:
-				delayEvent := e.(*DelayEvent)
-				delayEvent.functionToCall()
-			case TERMINATE:
-				s.isTerminating = true
-				terminateEvent := e.(*TerminateEvent)
-				if terminateEvent.err == nil {
-					for _, n := range s.nodes {
-						n.node.OnTerminate()
-					}
+				s.pushTimeStepEvent(e.Time() + s.options.TimeStep)
+			}
+		case MOVEMENT:
+			movementEvent := e.(*MovementEvent)
+			s.nextMovement(movementEvent.node)
+		case CONNECT:
+			connectEvent := e.(*ConnectEvent)
+			if s.options.ContactPrediction {
+				s.refreshPosition(connectEvent.nodeA)
+				s.refreshPosition(connectEvent.nodeB)
+			}
+			var lifetime time.Duration
+			if s.options.ContactPrediction || s.tracksConnectionAttempts() {
+				if d := s.completeDiscovery(connectEvent.nodeA, connectEvent.nodeB); d != nil {
+					lifetime = d.lifetime
 				}
-				s.isRunning = false
-				return terminateEvent.err
-			default:
-				panic("Simulator event error!")
+				s.peers.add(connectEvent.nodeA, connectEvent.nodeB)
 			}
-
-			if s.eventQueue.Len() == 0 {
+			s.connectNodes(connectEvent.nodeA, connectEvent.nodeB)
+			if lifetime > 0 {
+				s.drops[newNodePair(connectEvent.nodeA, connectEvent.nodeB)] = s.pushDisconnectEvent(s.time+lifetime, connectEvent.nodeA, connectEvent.nodeB)
+			}
+		case DISCONNECT:
+			disconnectEvent := e.(*DisconnectEvent)
+			dropped := s.completeDrop(disconnectEvent)
+			s.completeDeparture(disconnectEvent)
+			if s.options.ContactPrediction {
+				s.refreshPosition(disconnectEvent.nodeA)
+				s.refreshPosition(disconnectEvent.nodeB)
+			}
+			if s.options.ContactPrediction || dropped {
+				s.peers.remove(disconnectEvent.nodeA, disconnectEvent.nodeB)
+			}
+			s.disconnectNodes(disconnectEvent.nodeA, disconnectEvent.nodeB)
+			if dropped {
+				s.reconnect(disconnectEvent.nodeA, disconnectEvent.nodeB)
+			} else if s.options.ContactPrediction && s.options.Topography != nil {
+				// The obstacles may bring the nodes back into range before they change their movement
+				s.predictContact(disconnectEvent.nodeA, disconnectEvent.nodeB)
+			}
+			s.reconsiderDeclined(disconnectEvent.nodeA)
+			s.reconsiderDeclined(disconnectEvent.nodeB)
+		case ADD_NODE:
+			addNodeEvent := e.(*AddNodeEvent)
+			iNode := addNodeEvent.node
+			if iNode.removed {
 				break
-			} else {
-				e = heap.Pop(s.eventQueue).(Event)
 			}
+			s.nodes = append(s.nodes, iNode)
+			s.regionMap.AddNode(iNode)
+			iNode.dirty = true
+			iNode.startNode()
+			iNode.nodeID = iNode.node.ID()
+			s.scheduleRandomCrash(iNode)
+			s.startEnergy(iNode)
+			if s.options.ContactPrediction {
+				s.nextMovement(iNode)
+			}
+		case RM_NODE:
+			removeNodeEvent := e.(*RemoveNodeEvent)
+			s.removeNode(removeNodeEvent.node)
+		case RCV_MSG:
+			receiveEvent := e.(*ReceiveEvent)
+			if !receiveEvent.duplicate {
+				receiveEvent.origin.curBufferCount--
+			}
+			if receiveEvent.target.hasNeighbour(receiveEvent.origin) {
+				s.chargeBytes(receiveEvent.target, len(receiveEvent.packet), false)
+				packet := receiveEvent.packet
+				receiveEvent.target.node.OnReceivePacket(receiveEvent.peer, packet, receiveEvent.originNodeID)
+			}
+		case SEND_MSG:
+			sendEvent := e.(*SendEvent)
+			s.sendPacket(sendEvent)
+		case DELAY:
+			delayEvent := e.(*DelayEvent)
+			delayEvent.functionToCall()
+		case PARTITION:
+			s.startPartition(e.(*PartitionEvent).partition)
+		case HEAL:
+			s.healPartition(e.(*HealEvent).partition)
+		case CRASH:
+			crashEvent := e.(*CrashEvent)
+			s.crashNode(crashEvent.node, crashEvent.downtime)
+		case RESTART:
+			s.restartNode(e.(*RestartEvent).node)
+		case DEPLETE:
+			s.depleteNode(e.(*DepleteEvent).node)
+		case TERMINATE:
+			s.isTerminating = true
+			terminateEvent := e.(*TerminateEvent)
+			if terminateEvent.err == nil {
+				for _, n := range s.nodes {
+					n.node.OnTerminate()
+				}
+			}
+			s.isRunning = false
+			return true, terminateEvent.err
+		default:
+			panic("Simulator event error!")
+		}
+
+		if stop != nil && stop() {
+			break
 		}
 	}
-	return nil
+
+	if s.options.ContactPrediction {
+		s.refreshPositions(max(positionTime, s.time))
+	}
+	return false, nil
 }
 
 func (s *Simulator) updateLocations() {
-	deltaTime := 10 * time.Millisecond
+	deltaTime := s.options.TimeStep
 
 	// Update locations
 	for i := 0; i < len(s.nodes); i++ {
@@ -280,8 +612,16 @@ func (s *Simulator) updateLocations() {
 			s.nodes[i].movementInstruction = s.nodes[i].nodeMovement.RegisterMovements(s.nodes[i].coords)
 		} else {
 			node := s.nodes[i]
-			var delta_x, delta_y = calcSpeed(node, node.movementInstruction.Coords, node.movementInstruction.Time, time.Duration(deltaTime))
-			newCoords := Coordinate{node.coords.X + delta_x, node.coords.Y + delta_y}
+			var delta_x, delta_y, delta_z = calcSpeed(node, node.movementInstruction.Coords, node.movementInstruction.Time, time.Duration(deltaTime))
+			newCoords := Coordinate{node.coords.X + delta_x, node.coords.Y + delta_y, node.coords.Z + delta_z, node.coords.Floor}
+			if node.movementInstruction.Time <= deltaTime {
+				// The node changes floor when it arrives
+				newCoords.Floor = node.movementInstruction.Coords.Floor
+			}
+			if newCoords != node.coords {
+				node.dirty = true
+				s.markMoved(node)
+			}
 			s.regionMap.MoveNode(node, node.coords, newCoords)
 			node.coords = newCoords
 
@@ -289,60 +629,71 @@ func (s *Simulator) updateLocations() {
 		}
 	}
 
-	disconnects := []*DisconnectEvent{}
-	connects := []*ConnectEvent{}
+	// The connections between nodes that have not moved stay the same,
+	// so only the nodes around those that moved or can reconnect are updated
+	dirtyNodes := []*InternalNode{}
+	for _, node := range s.nodes {
+		if node.dirty {
+			dirtyNodes = append(dirtyNodes, node)
+		}
+	}
+	affectedRegions := s.regionMap.RegionsAround(dirtyNodes)
+
+	disconnects := make(map[nodePair]bool)
+	connects := make(map[nodePair]bool)
 
 	// Update connections
 	for i := 0; i < len(s.nodes); i++ {
 		nodeA := s.nodes[i]
+		if !nodeA.dirty && !affectedRegions[s.regionMap.CoordToRegionCoord(nodeA.coords)] {
+			continue
+		}
 		relevantNodes, oldPeers := s.regionMap.WithinRange(nodeA)
 
 		for _, oldPeer := range oldPeers {
-
-			shouldDisconnect := true
-			for _, e := range disconnects {
-				// Check if nodeA has already been part of a disconnect event
-				if e.nodeB.internalID == nodeA.internalID && e.nodeA.internalID == oldPeer.internalID {
-					shouldDisconnect = false
-				}
-			}
-			if shouldDisconnect {
+			// Check if nodeA has already been part of a disconnect event
+			if pair := newNodePair(nodeA, oldPeer); !disconnects[pair] {
 				// Remove peer
-				s.peers = removePeer(s.peers, nodeA, oldPeer)
+				s.peers.remove(nodeA, oldPeer)
 
-				// Add disconnect event to queue
-				s.pushDisconnectEvent(s.time+deltaTime, nodeA, oldPeer)
+				// Add disconnect event to queue, which replaces any spontaneous drop of the connection
+				s.cancelDrop(nodeA, oldPeer)
+				s.departures[pair] = s.pushDisconnectEvent(s.time+deltaTime, nodeA, oldPeer)
 
 				// Store disconnect event, so we don't add a symmetrical event later
-				disconnects = append(disconnects, s.NewDisconnectEvent(s.time+deltaTime, nodeA, oldPeer))
+				disconnects[pair] = true
 			}
 		}
 
 		for _, nodeB := range relevantNodes {
 			hasPeer := nodeA.hasNeighbour(nodeB)
-			if !hasPeer {
-
-				shouldConnect := true
-				for _, e := range connects {
-					// Check if nodeA has already been part of a disconnect event
-					if e.nodeB.internalID == nodeA.internalID && e.nodeA.internalID == nodeB.internalID {
-						shouldConnect = false
-					}
+			if !hasPeer && s.isDeclined(nodeA, nodeB) {
+				continue
+			} else if !hasPeer && s.tracksConnectionAttempts() {
+				if _, found := s.discoveries[newNodePair(nodeA, nodeB)]; !found {
+					s.pushDiscoveryConnectEvent(s.startDiscovery(nodeA, nodeB, s.time+deltaTime), nodeA, nodeB)
 				}
+			} else if pair := newNodePair(nodeA, nodeB); !hasPeer && !connects[pair] {
+				// Add peer
+				s.peers.add(nodeA, nodeB)
 
-				if shouldConnect {
-					// Add peer
-					simPeer := InternalPeer{nodeA, nodeB, make(map[string]interface{}), make(map[string]interface{})}
-					s.peers = append(s.peers, simPeer)
-
-					// Add connect event to queue
-					s.pushConnectEvent(s.time+deltaTime, nodeA, nodeB)
+				// Add connect event to queue
+				s.pushConnectEvent(s.time+deltaTime, nodeA, nodeB)
 
-					connects = append(connects, s.NewConnectEvent(s.time+deltaTime, nodeA, nodeB))
-				}
+				// Store connect event, so we don't add a symmetrical event later
+				connects[pair] = true
 			}
 		}
 	}
+
+	for _, node := range dirtyNodes {
+		node.dirty = false
+	}
+
+	if s.tracksConnectionAttempts() {
+		s.abortDiscoveriesOutOfRange()
+	}
+	s.forgetDeclinedOutOfRange()
 }
 
 func (s *Simulator) connectNodes(nodeA *InternalNode, nodeB *InternalNode) {
@@ -357,8 +708,18 @@ func (s *Simulator) disconnectNodes(nodeA *InternalNode, nodeB *InternalNode) {
 
 func (s *Simulator) sendPacket(sendEvent *SendEvent) {
 	peer := sendEvent.peer
+	if !sendEvent.duplicate {
+		peer.origin.dequeue(sendEvent)
+		if len(peer.origin.queue) > 0 {
+			s.scheduleSend(peer.origin)
+		}
+	}
+	if peer.origin.hasNeighbour(peer.target) {
+		// The packet is transmitted even if it is lost on the way
+		s.chargeBytes(peer.origin, len(sendEvent.packet), true)
+	}
 
-	if !sendEvent.shouldBeDropped && peer.origin.hasNeighbour(peer.target) {
+	if !sendEvent.shouldBeDropped && peer.origin.hasNeighbour(peer.target) && s.inLinkRange(peer.origin, peer.target) {
 		s.logDebug(fmt.Sprintf("simulator:packet:send:%d:%d", peer.origin.nodeID, peer.target.nodeID))
 
 		newPeer := Peer{
@@ -366,9 +727,12 @@ func (s *Simulator) sendPacket(sendEvent *SendEvent) {
 			origin: peer.target,
 		}
 
-		s.pushReceiveEvent(s.time+sendEvent.delay, peer.target, peer.origin, peer.origin.nodeID, newPeer, sendEvent.packet)
+		receiveEvent := s.pushReceiveEvent(s.time+sendEvent.delay, peer.target, peer.origin, peer.origin.nodeID, newPeer, sendEvent.packet)
+		receiveEvent.duplicate = sendEvent.duplicate
 	} else {
-		peer.origin.curBufferCount--
+		if !sendEvent.duplicate {
+			peer.origin.curBufferCount--
+		}
 		s.logDebug(fmt.Sprintf("simulator:packet:drop:%d:%d", peer.origin.nodeID, peer.target.nodeID))
 	}
 }
@@ -380,45 +744,227 @@ func (s *Simulator) Terminate() {
 }
 
 func (s *Simulator) AddNode(node Node, nodeMovement NodeMovement, delay time.Duration) {
+	s.AddNodeWithOptions(node, nodeMovement, delay, nil)
+}
+
+// AddNodeWithOptions adds a node like AddNode, configured by the given options. If options is nil, DefaultNodeOptions is used.
+func (s *Simulator) AddNodeWithOptions(node Node, nodeMovement NodeMovement, delay time.Duration, options *NodeOptions) {
+	if options == nil {
+		options = DefaultNodeOptions()
+	}
+	if options.BufferSize <= 0 {
+		panic("node buffer size must be positive")
+	}
+	if options.QueueDiscipline == nil {
+		panic("node needs a queue discipline")
+	}
+	if options.MaxConnections > 0 && options.PeerSelection == nil {
+		panic("node with limited connections needs a peer selection")
+	}
+	if options.Clock.Drift <= -1 {
+		panic("clock drift must be greater than -1")
+	}
+	bandwidth := options.Bandwidth
+	if bandwidth == nil {
+		bandwidth = s.options.Bandwidth
+	}
+
+	startPosition := nodeMovement.StartPosition()
 	var iNode = &InternalNode{
 		node:                node,
 		nodeID:              node.ID(),
 		internalID:          InternalID(s.random.Int()),
-		coords:              nodeMovement.StartPosition(),
-		movementInstruction: MovementInstruction{Coordinate{0, 0}, -1},
+		coords:              startPosition,
+		segmentStart:        startPosition,
+		movementInstruction: MovementInstruction{Coordinate{X: 0, Y: 0}, -1},
 		nodeMovement:        nodeMovement,
 		peers:               make(map[InternalID]Peer),
+		connectedAt:         make(map[InternalID]time.Duration),
+		maxConnections:      options.MaxConnections,
+		peerSelection:       options.PeerSelection,
+		crashModel:          options.Crashes,
+		persistState:        options.PersistState,
+		energy:              newEnergyState(options.Energy),
+		clock:               options.Clock,
 		random:              s.random,
 		sim:                 s,
 		curBufferCount:      0,
-		bufferSize:          400,
+		bufferSize:          options.BufferSize,
+		queueDiscipline:     options.QueueDiscipline,
+		queue:               []*SendEvent{},
+		bandwidth:           bandwidth,
+		transmitFactor:      rangeFactor(options.TransmitPowerOffset),
+		receiveFactor:       rangeFactor(-options.ReceiverSensitivityOffset),
 		lastMessageSent:     time.Duration(0),
+		lastMessageReceived: time.Duration(0),
 		data:                make(map[string]interface{}),
 	}
 
+	s.registeredNodes = append(s.registeredNodes, iNode)
+	s.regionMap.registerRange(iNode)
 	s.pushAddNodeEvent(delay, iNode)
 }
 
+// RemoveNode removes the given node from the simulation at the given time.
+// All of its connections are closed, packets in flight to or from it are dropped and OnTerminate is called on it.
+func (s *Simulator) RemoveNode(node Node, at time.Duration) {
+	iNode := s.findInternalNode(node)
+	if iNode == nil {
+		panic("could not find node to remove in Simulator.RemoveNode")
+	}
+
+	s.pushRemoveNodeEvent(at, iNode)
+}
+
+func (s *Simulator) findInternalNode(node Node) *InternalNode {
+	for _, iNode := range s.registeredNodes {
+		if iNode.node == node {
+			return iNode
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) removeNode(iNode *InternalNode) {
+	if iNode.removed {
+		return
+	}
+	iNode.removed = true
+
+	index := slices.Index(s.nodes, iNode)
+	if index < 0 {
+		// The node was removed before it was added
+		return
+	}
+
+	s.dropNodeEvents(iNode)
+	s.dropEvents(func(e Event) bool {
+		switch e.EventType() {
+		case MOVEMENT:
+			return e.(*MovementEvent).node == iNode
+		case CRASH:
+			return e.(*CrashEvent).node == iNode
+		case RESTART:
+			return e.(*RestartEvent).node == iNode
+		}
+		return false
+	})
+	s.forgetPredictions(iNode)
+	s.forgetDiscoveries(iNode)
+	s.forgetDeclined(iNode)
+	s.forgetDrops(iNode)
+
+	for _, internalID := range utils.ShuffleMapKeys(iNode.random, iNode.peers) {
+		peer := iNode.peers[internalID].target
+		s.disconnectNodes(iNode, peer)
+		s.reconsiderDeclined(peer)
+	}
+
+	s.peers.removeNode(iNode)
+
+	s.nodes = slices.Delete(s.nodes, index, index+1)
+	s.regionMap.RemoveNode(iNode)
+	s.updateEnergy(iNode)
+
+	iNode.node.OnTerminate()
+}
+
+// dropNodeEvents drops the pending events that involve the node, including connections that have not been established yet,
+// except for its movements
+func (s *Simulator) dropNodeEvents(iNode *InternalNode) {
+	s.dropQueuedPackets(iNode)
+	s.dropEvents(func(e Event) bool {
+		switch e.EventType() {
+		case CONNECT:
+			connectEvent := e.(*ConnectEvent)
+			return connectEvent.nodeA == iNode || connectEvent.nodeB == iNode
+		case DISCONNECT:
+			disconnectEvent := e.(*DisconnectEvent)
+			return disconnectEvent.nodeA == iNode || disconnectEvent.nodeB == iNode
+		case SEND_MSG:
+			// The packets in the buffers have been dropped by dropQueuedPackets
+			sendEvent := e.(*SendEvent)
+			return sendEvent.origin == iNode || sendEvent.target == iNode
+		case RCV_MSG:
+			receiveEvent := e.(*ReceiveEvent)
+			if receiveEvent.origin == iNode || receiveEvent.target == iNode {
+				if !receiveEvent.duplicate {
+					receiveEvent.origin.curBufferCount--
+				}
+				return true
+			}
+		case DELAY:
+			delayEvent := e.(*DelayEvent)
+			return delayEvent.node == iNode
+		}
+		return false
+	})
+}
+
+// dropEvents removes all events from the event queue for which shouldDrop returns true
+func (s *Simulator) dropEvents(shouldDrop func(e Event) bool) {
+	*s.eventQueue = slices.DeleteFunc(*s.eventQueue, shouldDrop)
+	for i, e := range *s.eventQueue {
+		e.base().index = i
+	}
+	heap.Init(s.eventQueue)
+}
+
 func nodeDistSquared(nodeA *InternalNode, nodeB *InternalNode) float64 {
 	diffX := math.Abs(float64(nodeA.coords.X) - float64(nodeB.coords.X))
 	diffY := math.Abs(float64(nodeA.coords.Y) - float64(nodeB.coords.Y))
+	diffZ := math.Abs(float64(nodeA.coords.Z) - float64(nodeB.coords.Z))
 
-	return diffX*diffX + diffY*diffY
+	return diffX*diffX + diffY*diffY + diffZ*diffZ
 }
 
-func calcSpeed(node *InternalNode, coord Coordinate, time time.Duration, timeSinceLast time.Duration) (float64, float64) {
+func calcSpeed(node *InternalNode, coord Coordinate, time time.Duration, timeSinceLast time.Duration) (float64, float64, float64) {
 	if int64(time) == 0 {
-		return 0, 0
+		return 0, 0, 0
 	}
 	var delta_x = ((coord.X - node.coords.X) / (float64(time) / 1000000000.0)) * (float64(timeSinceLast) / 1000000000.0)
 	var delta_y = ((coord.Y - node.coords.Y) / (float64(time) / 1000000000.0)) * (float64(timeSinceLast) / 1000000000.0)
-	return delta_x, delta_y
+	var delta_z = ((coord.Z - node.coords.Z) / (float64(time) / 1000000000.0)) * (float64(timeSinceLast) / 1000000000.0)
+	return delta_x, delta_y, delta_z
+}
+
+// dropFromBuffer passes a BufferDropEvent for a packet dropped by the queue discipline to the loggers
+func (s *Simulator) dropFromBuffer(origin *InternalNode, targetNodeID NodeID, packet []byte, queued bool) {
+	dropEvent := s.newBufferDropEvent(origin, targetNodeID, packet, queued)
+	s.currentSequenceNumber++
+	s.updateLoggers(dropEvent)
+}
+
+// flushLoggers flushes the loggers which buffer their output, and returns the first error
+func (s *Simulator) flushLoggers() error {
+	var err error
+	for _, logger := range s.loggers {
+		if flushing, ok := logger.(FlushingLogger); ok {
+			if flushErr := flushing.Flush(); err == nil {
+				err = flushErr
+			}
+		}
+	}
+	return err
 }
 
 func (s *Simulator) updateLoggers(e Event) {
 	for _, logger := range s.loggers {
 		logger.NewEvent(e)
 	}
+
+	for _, node := range s.movedNodes {
+		node.moved = false
+	}
+	s.movedNodes = s.movedNodes[:0]
+}
+
+// markMoved adds the node to the nodes that moved since the last event was passed to the loggers
+func (s *Simulator) markMoved(node *InternalNode) {
+	if !node.moved {
+		node.moved = true
+		s.movedNodes = append(s.movedNodes, node)
+	}
 }
 
 func (s *Simulator) logDebug(str string) {
@@ -428,14 +974,14 @@ func (s *Simulator) logDebug(str string) {
 }
 
 func (s *Simulator) getPeerData(peer *Peer, node *InternalNode) *map[string]any {
-	i := getPeerIndex(s.peers, peer.origin, peer.target)
-	if i < 0 {
+	internalPeer := s.peers.get(peer.origin, peer.target)
+	if internalPeer == nil {
 		return nil
 	}
-	if s.peers[i].a == node {
-		return &s.peers[i].dataA
-	} else if s.peers[i].b == node {
-		return &s.peers[i].dataB
+	if internalPeer.a == node {
+		return &internalPeer.dataA
+	} else if internalPeer.b == node {
+		return &internalPeer.dataB
 	}
 	return nil
 }
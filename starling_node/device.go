@@ -15,22 +15,32 @@ import (
 type NodeDevice struct {
 	node              *Node
 	contactsContainer *device.MemoryContactsContainer
+	// muted discards the logs, packets and timers of the protocol, and gives it a random source of its own,
+	// such that it does not affect the simulation
+	muted bool
 }
 
 func NewNodeDevice(node *Node) *NodeDevice {
 	return &NodeDevice{
 		node:              node,
 		contactsContainer: device.NewMemoryContactsContainer(),
+		muted:             false,
 	}
 }
 
 // Log implements device.Device.
 func (dev *NodeDevice) Log(message string) {
+	if dev.muted {
+		return
+	}
 	dev.node.log(message)
 }
 
 // SendPacket implements device.Device.
 func (dev *NodeDevice) SendPacket(address device.DeviceAddress, packet []byte) {
+	if dev.muted {
+		return
+	}
 	peer := dev.node.peers[address]
 	peer.SendPacket(packet)
 }
@@ -55,6 +65,8 @@ func (dev *NodeDevice) ProcessMessage(session device.SessionID, message []byte)
 
 // SyncStateChanged implements device.Device.
 func (dev *NodeDevice) SyncStateChanged(contact device.ContactID, stateUpdate []byte) {
+	dev.node.syncStates[contact] = stateUpdate
+
 	data, err := sync.DecodeModelFromJSON(stateUpdate)
 	if err != nil {
 		panic(fmt.Sprintf("failed to unmarshal json in SyncStateChanged: %s", err.Error()))
@@ -70,6 +82,9 @@ func (dev *NodeDevice) Rand() *rand.Rand {
 	if dev.node.random == nil {
 		panic("Node device 'random' was nil")
 	}
+	if dev.muted {
+		return rand.New(rand.NewSource(0))
+	}
 
 	return dev.node.random
 }
@@ -101,6 +116,9 @@ func (dev *NodeDevice) SessionEstablished(session device.SessionID, contact devi
 
 // Delay implements device.Device.
 func (n *NodeDevice) Delay(action func(), duration time.Duration) {
+	if n.muted {
+		return
+	}
 	n.node.sim.DelayBy(action, duration)
 }
 
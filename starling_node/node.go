@@ -11,9 +11,14 @@ import (
 )
 
 type Node struct {
-	proto     *starling.Protocol
-	peers     map[device.DeviceAddress]simulator.Peer
-	scenarios []Scenario
+	proto   *starling.Protocol
+	device  *NodeDevice
+	options *device.ProtocolOptions
+	peers   map[device.DeviceAddress]simulator.Peer
+	// lastActive is the local time at which each peer was connected or last sent a packet, see SelectPeer
+	lastActive map[device.DeviceAddress]time.Time
+	scenarios  []Scenario
+	syncStates map[device.ContactID][]byte
 
 	id                   simulator.NodeID
 	sim                  *simulator.NodeArguments
@@ -21,6 +26,11 @@ type Node struct {
 	random               *rand.Rand
 
 	beforeStartLogs []string
+	// startedAt is the local time at which the node was started
+	startedAt time.Time
+	// scenarioStart is the local time at which a scenario that is restarted without having been started was started,
+	// which happens when the node is restored from a checkpoint
+	scenarioStart time.Time
 }
 
 func NewNode(random *rand.Rand, id *simulator.NodeID, transmissionBehavior simulator.TransmissionBehavior, options *device.ProtocolOptions) *Node {
@@ -31,18 +41,34 @@ func NewNode(random *rand.Rand, id *simulator.NodeID, transmissionBehavior simul
 
 	node := &Node{
 		proto:                nil,
+		device:               nil,
+		options:              options,
 		peers:                make(map[device.DeviceAddress]simulator.Peer),
+		lastActive:           make(map[device.DeviceAddress]time.Time),
+		syncStates:           make(map[device.ContactID][]byte),
 		id:                   *id,
 		transmissionBehavior: transmissionBehavior,
 		random:               random,
 		beforeStartLogs:      []string{},
+		startedAt:            time.Time{},
+		scenarioStart:        time.Time{},
 	}
 
-	node.proto = starling.NewProtocol(NewNodeDevice(node), options)
+	node.device = NewNodeDevice(node)
+	node.proto = starling.NewProtocol(node.device, options)
 
 	return node
 }
 
+// resetProtocol replaces the protocol of the node with a new instance using the given contacts,
+// such that all other protocol state is lost.
+func (n *Node) resetProtocol(contactsContainer *device.MemoryContactsContainer) {
+	n.device = NewNodeDevice(n)
+	n.device.contactsContainer = contactsContainer
+	n.proto = starling.NewProtocol(n.device, n.options)
+	n.proto.LoadPersistedState()
+}
+
 func idToAddr(id simulator.NodeID) device.DeviceAddress {
 	return device.DeviceAddress(fmt.Sprint(id))
 }
@@ -53,6 +79,7 @@ func (n *Node) UpdateTransmissionBehaviour(transmissionBehavior simulator.Transm
 
 func (n *Node) OnConnect(peer simulator.Peer, id simulator.NodeID) {
 	n.peers[idToAddr(id)] = peer
+	n.lastActive[idToAddr(id)] = n.sim.Now()
 	n.proto.OnConnection(idToAddr(id))
 
 	for _, scenario := range n.scenarios {
@@ -62,6 +89,7 @@ func (n *Node) OnConnect(peer simulator.Peer, id simulator.NodeID) {
 
 func (n *Node) OnDisconnect(peer simulator.Peer, id simulator.NodeID) {
 	delete(n.peers, idToAddr(id))
+	delete(n.lastActive, idToAddr(id))
 	n.proto.OnDisconnection(idToAddr(id))
 
 	for _, scenario := range n.scenarios {
@@ -70,6 +98,7 @@ func (n *Node) OnDisconnect(peer simulator.Peer, id simulator.NodeID) {
 }
 
 func (n *Node) OnReceivePacket(peer simulator.Peer, packet []byte, id simulator.NodeID) {
+	n.lastActive[idToAddr(id)] = n.sim.Now()
 	n.proto.ReceivePacket(idToAddr(id), packet)
 
 	for _, scenario := range n.scenarios {
@@ -86,6 +115,12 @@ func (n *Node) OnReceiveData(session device.SessionID, message []byte) {
 func (n *Node) OnStart(sim simulator.NodeArguments) {
 	n.sim = &sim
 
+	if sim.Restarts > 0 {
+		n.restart(sim.PersistedState)
+		return
+	}
+
+	n.startedAt = sim.Now()
 	for _, message := range n.beforeStartLogs {
 		n.log(message)
 	}
@@ -95,6 +130,30 @@ func (n *Node) OnStart(sim simulator.NodeArguments) {
 	}
 }
 
+// restart rebuilds the protocol after a crash, keeping the contacts of the node.
+// The synchronization states are only kept if the node has persisted its state.
+func (n *Node) restart(persistedState bool) {
+	n.logf("node:restart:%d", n.id)
+
+	n.peers = make(map[device.DeviceAddress]simulator.Peer)
+	n.lastActive = make(map[device.DeviceAddress]time.Time)
+	syncStates := n.syncStates
+	n.syncStates = make(map[device.ContactID][]byte)
+	n.resetProtocol(n.device.contactsContainer)
+
+	if persistedState {
+		n.syncStates = syncStates
+		if err := n.loadSyncStates(syncStates); err != nil {
+			n.sim.Terminate(err)
+			return
+		}
+	}
+
+	for _, scenario := range n.scenarios {
+		scenario.OnRestart(n)
+	}
+}
+
 func (n *Node) ID() simulator.NodeID {
 	return n.id
 }
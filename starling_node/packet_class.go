@@ -0,0 +1,34 @@
+package starling_node
+
+import (
+	"github.com/starling-protocol/starling/network_layer"
+	"github.com/starling-protocol/starling/packet_layer"
+)
+
+// PacketClass classifies a Starling packet for simulator.PriorityDrop.
+// Session data is the most important, followed by route replies, route errors and route requests.
+// Packets which cannot be decoded, such as fragments of larger messages, are treated as session data.
+func PacketClass(packet []byte) int {
+	decoder := packet_layer.NewPacketDecoder()
+	decoder.AppendPacket(packet)
+	decodedMsg, err := decoder.ReadMessage()
+	if err != nil {
+		return 3
+	}
+
+	networkPacket, err := network_layer.DecodeRoutingPacket(decodedMsg)
+	if err != nil {
+		return 3
+	}
+
+	switch networkPacket.PacketType() {
+	case network_layer.SESS:
+		return 3
+	case network_layer.RREP:
+		return 2
+	case network_layer.RERR:
+		return 1
+	default:
+		return 0
+	}
+}
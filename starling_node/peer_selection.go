@@ -0,0 +1,35 @@
+package starling_node
+
+import (
+	"time"
+
+	"github.com/starling-protocol/simulator"
+)
+
+// idlePeerTimeout is the time after which a peer that has not sent any packets may be evicted for a new candidate
+const idlePeerTimeout = 10 * time.Second
+
+// SelectPeer implements simulator.PeerSelector, which is used with simulator.ProtocolPeers.
+// The node keeps the peers it has received packets from recently, and evicts the peer that has been idle
+// the longest for the candidate, unless every peer has been active within idlePeerTimeout.
+func (n *Node) SelectPeer(peers []simulator.PeerInfo, candidate simulator.PeerInfo) (evict simulator.NodeID, accept bool) {
+	now := n.sim.Now()
+
+	idlest := -1
+	var idlestActive time.Time
+	for i, peer := range peers {
+		active := n.lastActive[idToAddr(peer.NodeID)]
+		if now.Sub(active) < idlePeerTimeout {
+			continue
+		}
+		if idlest < 0 || active.Before(idlestActive) {
+			idlest = i
+			idlestActive = active
+		}
+	}
+
+	if idlest < 0 {
+		return 0, false
+	}
+	return peers[idlest].NodeID, true
+}
@@ -12,6 +12,7 @@ import (
 type EmptyScenario struct{}
 
 func (e *EmptyScenario) OnStart(node *Node)                                                      {}
+func (e *EmptyScenario) OnRestart(node *Node)                                                    {}
 func (e *EmptyScenario) OnConnect(node *Node, address device.DeviceAddress)                      {}
 func (e *EmptyScenario) OnDisconnect(node *Node, address device.DeviceAddress)                   {}
 func (e *EmptyScenario) OnLog(node *Node, message string)                                        {}
@@ -28,6 +29,10 @@ func (e *EmptyScenario) OnTerminate(node *Node) {}
 
 type Scenario interface {
 	OnStart(node *Node)
+	// OnRestart is called instead of OnStart when the node restarts after a crash or is restored from a checkpoint,
+	// which has lost its sessions and timers. A restored scenario may not have been started in this run, in which case
+	// it was started before the checkpoint was taken, at the local time node.scenarioStart.
+	OnRestart(node *Node)
 	OnConnect(node *Node, address device.DeviceAddress)
 	OnDisconnect(node *Node, address device.DeviceAddress)
 	OnLog(node *Node, message string)
@@ -45,23 +50,40 @@ type ScenarioBroadcastRouteRequest struct {
 	EmptyScenario
 	didBroadcast bool
 	delay        time.Duration
+	startedAt    time.Time
 }
 
 func BroadcastRouteRequestScenario(delay time.Duration) *ScenarioBroadcastRouteRequest {
 	return &ScenarioBroadcastRouteRequest{
 		didBroadcast: false,
 		delay:        delay,
+		startedAt:    time.Time{},
 	}
 }
 
 func (s *ScenarioBroadcastRouteRequest) OnStart(node *Node) {
 	node.logf("scenario:broadcast_rreq:start:%d", node.ID())
+	s.startedAt = node.sim.Now()
+	s.schedule(node, s.delay)
+}
 
+// OnRestart schedules the broadcast again, since the crash has dropped its timer
+func (s *ScenarioBroadcastRouteRequest) OnRestart(node *Node) {
+	if s.startedAt.IsZero() {
+		s.startedAt = node.scenarioStart
+		s.didBroadcast = remainingDelay(node, s.startedAt, s.delay) == 0
+	}
+	if !s.didBroadcast {
+		s.schedule(node, remainingDelay(node, s.startedAt, s.delay))
+	}
+}
+
+func (s *ScenarioBroadcastRouteRequest) schedule(node *Node, delay time.Duration) {
 	node.sim.DelayBy(func() {
 		node.logf("scenario:broadcast_rreq:send:%d", node.ID())
 		node.proto.BroadcastRouteRequest()
 		s.didBroadcast = true
-	}, s.delay)
+	}, delay)
 }
 
 func (s *ScenarioBroadcastRouteRequest) DidBroadcast() bool {
@@ -133,6 +155,17 @@ func (s *ScenarioMulti) OnStart(node *Node) {
 	}
 }
 
+func (s *ScenarioMulti) OnRestart(node *Node) {
+	if !s.didActivate && !node.scenarioStart.IsZero() {
+		s.didActivate = true
+	}
+	if s.didActivate {
+		for _, s := range s.scenarios {
+			s.OnRestart(node)
+		}
+	}
+}
+
 type ScenarioReply struct {
 	EmptyScenario
 	contact        device.ContactID
@@ -190,12 +223,15 @@ type ScenarioCount struct {
 	EmptyScenario
 	count    int
 	scenario Scenario
+	// startedOn is the node that the scenario was started on when the count reached zero
+	startedOn *Node
 }
 
 func CountScenario(count int, scenario Scenario) *ScenarioCount {
 	return &ScenarioCount{
-		count:    count,
-		scenario: scenario,
+		count:     count,
+		scenario:  scenario,
+		startedOn: nil,
 	}
 }
 
@@ -207,13 +243,23 @@ func (s *ScenarioCount) OnStart(node *Node) {
 	s.count -= 1
 
 	if s.count == 0 && s.scenario != nil {
+		s.startedOn = node
 		s.scenario.OnStart(node)
 	}
 }
 
+func (s *ScenarioCount) OnRestart(node *Node) {
+	if s.startedOn == node {
+		s.scenario.OnRestart(node)
+	}
+}
+
 type ScenarioAll struct {
 	triggers []*ScenarioAllEvent
 	scenario Scenario
+	// startedBy is the trigger which started the scenario when all of them had been triggered
+	startedBy *ScenarioAllEvent
+	startedOn *Node
 }
 
 type ScenarioAllEvent struct {
@@ -224,8 +270,10 @@ type ScenarioAllEvent struct {
 
 func AllEventsScenario() *ScenarioAll {
 	return &ScenarioAll{
-		triggers: []*ScenarioAllEvent{},
-		scenario: nil,
+		triggers:  []*ScenarioAllEvent{},
+		scenario:  nil,
+		startedBy: nil,
+		startedOn: nil,
 	}
 }
 
@@ -253,29 +301,71 @@ func (s *ScenarioAllEvent) OnStart(node *Node) {
 	s.triggered = true
 
 	if s.parent.scenario != nil && s.parent.AllTriggered() {
+		s.parent.startedBy = s
+		s.parent.startedOn = node
 		s.parent.scenario.OnStart(node)
 	}
 }
 
+func (s *ScenarioAllEvent) OnRestart(node *Node) {
+	if s.parent.startedBy == s && s.parent.startedOn == node {
+		s.parent.scenario.OnRestart(node)
+	}
+}
+
 type ScenarioDelay struct {
 	EmptyScenario
-	scenario Scenario
-	delay    time.Duration
+	scenario  Scenario
+	delay     time.Duration
+	startedAt time.Time
+	done      bool
 }
 
 func DelayScenario(scenario Scenario, delay time.Duration) *ScenarioDelay {
 	return &ScenarioDelay{
-		scenario: scenario,
-		delay:    delay,
+		scenario:  scenario,
+		delay:     delay,
+		startedAt: time.Time{},
+		done:      false,
 	}
 }
 
 func (s *ScenarioDelay) OnStart(node *Node) {
+	s.startedAt = node.sim.Now()
+	s.schedule(node, s.delay)
+}
+
+// OnRestart waits for the rest of the delay, since the crash has dropped its timer, or restarts the scenario if it has been started
+func (s *ScenarioDelay) OnRestart(node *Node) {
+	if s.startedAt.IsZero() {
+		s.startedAt = node.scenarioStart
+		s.done = remainingDelay(node, s.startedAt, s.delay) == 0
+		if s.done {
+			// The scenario was started when the delay ended
+			scenarioStart := node.scenarioStart
+			node.scenarioStart = s.startedAt.Add(s.delay)
+			defer func() { node.scenarioStart = scenarioStart }()
+		}
+	}
+	if s.done {
+		s.scenario.OnRestart(node)
+	} else {
+		s.schedule(node, remainingDelay(node, s.startedAt, s.delay))
+	}
+}
+
+func (s *ScenarioDelay) schedule(node *Node, delay time.Duration) {
 	node.sim.DelayBy(func() {
 		s.delayDone(node)
-	}, s.delay)
+	}, delay)
 }
 
 func (s *ScenarioDelay) delayDone(node *Node) {
+	s.done = true
 	s.scenario.OnStart(node)
 }
+
+// remainingDelay returns what remains of a delay which started at the given local time of the node
+func remainingDelay(node *Node, startedAt time.Time, delay time.Duration) time.Duration {
+	return max(0, delay-node.sim.Now().Sub(startedAt))
+}
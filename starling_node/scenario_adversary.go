@@ -4,23 +4,40 @@ import "time"
 
 type ScenarioSpamRouteRequests struct {
 	EmptyScenario
-	delay time.Duration
+	delay     time.Duration
+	startedAt time.Time
 }
 
 func SpamRouteRequestScenario(delay time.Duration) *ScenarioSpamRouteRequests {
 	return &ScenarioSpamRouteRequests{
-		delay: delay,
+		delay:     delay,
+		startedAt: time.Time{},
 	}
 }
 
 func (s *ScenarioSpamRouteRequests) OnStart(node *Node) {
 	node.logf("scenario:spam_rreq:start:%d", node.ID())
+	s.startedAt = node.sim.Now()
 
 	node.sim.DelayBy(func() {
 		s.broadcast_route_request(node)
 	}, s.delay)
 }
 
+// OnRestart continues broadcasting at the same interval, since the crash has dropped the timer
+func (s *ScenarioSpamRouteRequests) OnRestart(node *Node) {
+	if s.startedAt.IsZero() {
+		s.startedAt = node.scenarioStart
+	}
+	var next time.Duration
+	if s.delay > 0 {
+		next = s.delay - node.sim.Now().Sub(s.startedAt)%s.delay
+	}
+	node.sim.DelayBy(func() {
+		s.broadcast_route_request(node)
+	}, next)
+}
+
 func (s *ScenarioSpamRouteRequests) broadcast_route_request(node *Node) {
 	node.proto.BroadcastRouteRequest()
 
@@ -153,6 +153,14 @@ func (s *ScenarioEvent) OnEvent(scenario Scenario) *ScenarioEvent {
 
 func (s *ScenarioEvent) OnStart(node *Node) {}
 
+func (s *ScenarioEvent) OnRestart(node *Node) {
+	if s.hasStarted {
+		for _, scenario := range s.scenarios {
+			scenario.OnRestart(node)
+		}
+	}
+}
+
 func (s *ScenarioEvent) OnConnect(node *Node, address device.DeviceAddress) {
 	if s.hasStarted {
 		for _, scenario := range s.scenarios {
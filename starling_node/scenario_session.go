@@ -3,6 +3,7 @@ package starling_node
 import (
 	"time"
 
+	"github.com/starling-protocol/simulator"
 	"github.com/starling-protocol/starling/device"
 )
 
@@ -92,6 +93,14 @@ func (s *ScenarioSession) OnStart(node *Node) {
 	s.establishConnection(node)
 }
 
+// OnRestart breaks the session, which the crash has lost, and establishes it again
+func (s *ScenarioSession) OnRestart(node *Node) {
+	if s.sessionID != nil {
+		s.sessionLost()
+	}
+	s.establishConnection(node)
+}
+
 func (s *ScenarioSession) establishConnection(node *Node) {
 	node.sim.DelayBy(func() {
 		node.proto.BroadcastRouteRequest()
@@ -136,15 +145,17 @@ func (s *ScenarioSession) OnSessionEstablished(node *Node, session device.Sessio
 	}
 }
 
-func (s *ScenarioSession) OnSessionBroken(node *Node, session device.SessionID) {
-	if s.sessionID != nil && *s.sessionID == session {
-		s.orchestrator.count -= 1
-		if s.orchestrator.count <= 0 {
-			s.orchestrator.sessionID = nil
+func (s *ScenarioSession) OnMessageDelivered(node *Node, messageID device.MessageID) {
+	for _, sendDataScenario := range s.sendDataScenarios {
+		if sendDataScenario.DidSend() && sendDataScenario.eventArgs.messageID == messageID {
+			sendDataScenario.acknowledged = true
 		}
+	}
+}
 
-		s.didBreak = true
-		s.sessionID = nil
+func (s *ScenarioSession) OnSessionBroken(node *Node, session device.SessionID) {
+	if s.sessionID != nil && *s.sessionID == session {
+		s.sessionLost()
 
 		if s.maintainSession {
 			s.establishConnection(node)
@@ -152,12 +163,23 @@ func (s *ScenarioSession) OnSessionBroken(node *Node, session device.SessionID)
 	}
 }
 
+func (s *ScenarioSession) sessionLost() {
+	s.orchestrator.count -= 1
+	if s.orchestrator.count <= 0 {
+		s.orchestrator.sessionID = nil
+	}
+
+	s.didBreak = true
+	s.sessionID = nil
+}
+
 type ScenarioSendData struct {
 	EmptyScenario
 	context          *ScenarioSession
 	data             string
 	didSendOnSession *device.SessionID
 	didDeliver       bool
+	acknowledged     bool // acknowledged is set as soon as the node reports the delivery, unlike didDeliver which is set by a delivered event
 	eventArgs        *messageDeliveredArgs
 }
 
@@ -167,6 +189,7 @@ func (s *ScenarioSession) SendDataScenario(data string) *ScenarioSendData {
 		data:             data,
 		didSendOnSession: nil,
 		didDeliver:       false,
+		acknowledged:     false,
 		eventArgs: &messageDeliveredArgs{
 			messageID: 0,
 		},
@@ -205,6 +228,23 @@ func (s *ScenarioSendData) DidSend() bool {
 	return s.didSendOnSession != nil
 }
 
+// DidDeliver reports whether a delivered event has observed the delivery of the data
+func (s *ScenarioSendData) DidDeliver() bool {
+	return s.didDeliver
+}
+
+// AllDelivered returns a stop predicate for simulator.RunOptions, which is true when the node has acknowledged the delivery of all the data
+func AllDelivered(sends ...*ScenarioSendData) func(s *simulator.Simulator) bool {
+	return func(s *simulator.Simulator) bool {
+		for _, send := range sends {
+			if !send.acknowledged {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func (s *ScenarioSendData) DeliveredEvent() Event {
 	return Event{
 		eventType: eventMessageDelivered,
@@ -1,6 +1,8 @@
 package starling_node
 
 import (
+	"time"
+
 	"github.com/starling-protocol/starling/device"
 	"github.com/starling-protocol/starling/sync"
 )
@@ -107,6 +109,7 @@ type ScenarioStoreSyncState struct {
 	syncState *sync.Model
 	syncGoal  SyncGoal
 	nodeID    int
+	updatedAt time.Time
 }
 
 type SyncGoal struct {
@@ -137,6 +140,7 @@ func StoreSyncStateScenario(contact device.ContactID, syncGoal SyncGoal, nodeID
 func (s *ScenarioStoreSyncState) OnSyncStateChanged(node *Node, contact device.ContactID, stateUpdate sync.Model) {
 	if contact == s.contact {
 		s.syncState = &stateUpdate
+		s.updatedAt = node.SimulatorTime()
 	}
 }
 
@@ -144,6 +148,11 @@ func (s *ScenarioStoreSyncState) GetSyncState() *sync.Model {
 	return s.syncState
 }
 
+// UpdatedAt returns the simulated time of the last change to the sync state
+func (s *ScenarioStoreSyncState) UpdatedAt() time.Time {
+	return s.updatedAt
+}
+
 func (s *ScenarioStoreSyncState) GetSyncGoal() SyncGoal {
 	return s.syncGoal
 }
@@ -0,0 +1,129 @@
+package starling_node
+
+import (
+	"bytes"
+	"encoding/gob"
+	"slices"
+	"time"
+
+	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/starling/device"
+)
+
+type nodeSnapshot struct {
+	ID         simulator.NodeID
+	Links      []device.SharedSecret
+	Groups     []device.SharedSecret
+	SyncStates map[device.ContactID][]byte
+	StartedAt  time.Time
+}
+
+// Snapshot implements simulator.NodeSnapshotter.
+// Only the contacts, the latest synchronization states and the start time of the node are saved,
+// the sessions of the protocol and the state of the scenarios are not.
+func (n *Node) Snapshot() ([]byte, error) {
+	contacts := n.device.contactsContainer
+
+	snapshot := nodeSnapshot{
+		ID:         n.id,
+		Links:      []device.SharedSecret{},
+		Groups:     []device.SharedSecret{},
+		SyncStates: n.syncStates,
+		StartedAt:  n.startedAt,
+	}
+
+	for _, contact := range contacts.AllLinks() {
+		secret, err := contacts.ContactSecret(contact)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Links = append(snapshot.Links, secret)
+	}
+
+	for _, contact := range contacts.AllGroups() {
+		secret, err := contacts.ContactSecret(contact)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Groups = append(snapshot.Groups, secret)
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(snapshot)
+	return buf.Bytes(), err
+}
+
+// OnRestore implements simulator.NodeSnapshotter.
+// The protocol is rebuilt from the saved contacts and synchronization states, and is told about the existing connections
+// without any effect on the simulation. The scenarios are then restarted as after a crash, see Scenario.OnRestart,
+// where scenarios that were waiting for an event or a count when the checkpoint was taken wait for it again.
+func (n *Node) OnRestore(sim simulator.NodeArguments, data []byte, connections []simulator.Connection) error {
+	var snapshot nodeSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	n.id = snapshot.ID
+	n.sim = &sim
+	n.startedAt = snapshot.StartedAt
+	n.peers = make(map[device.DeviceAddress]simulator.Peer)
+	n.lastActive = make(map[device.DeviceAddress]time.Time)
+	n.syncStates = make(map[device.ContactID][]byte)
+
+	contactsContainer := device.NewMemoryContactsContainer()
+	for _, secret := range snapshot.Links {
+		if _, err := contactsContainer.NewLink(secret); err != nil {
+			return err
+		}
+	}
+	for _, secret := range snapshot.Groups {
+		if _, err := contactsContainer.JoinGroup(secret); err != nil {
+			return err
+		}
+	}
+
+	n.resetProtocol(contactsContainer)
+	if err := n.loadSyncStates(snapshot.SyncStates); err != nil {
+		return err
+	}
+
+	n.device.muted = true
+	for _, connection := range connections {
+		n.peers[idToAddr(connection.ID)] = connection.Peer
+		n.lastActive[idToAddr(connection.ID)] = sim.Now()
+		n.proto.OnConnection(idToAddr(connection.ID))
+	}
+	n.device.muted = false
+
+	// The scenarios were started along with the node before the checkpoint was taken
+	n.scenarioStart = n.startedAt
+	for _, scenario := range n.scenarios {
+		scenario.OnRestart(n)
+	}
+	n.scenarioStart = time.Time{}
+	return nil
+}
+
+// loadSyncStates loads the synchronization states into the protocol in the order of their contacts
+func (n *Node) loadSyncStates(syncStates map[device.ContactID][]byte) error {
+	if n.options == nil || !n.options.EnableSync {
+		return nil
+	}
+
+	contacts := []device.ContactID{}
+	for contact := range syncStates {
+		contacts = append(contacts, contact)
+	}
+	slices.Sort(contacts)
+
+	for _, contact := range contacts {
+		state := syncStates[contact]
+		if state == nil {
+			continue
+		}
+		if err := n.proto.SyncLoadState(contact, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
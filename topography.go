@@ -0,0 +1,103 @@
+package simulator
+
+// An Obstacle is a polygon which attenuates links that cross it
+type Obstacle struct {
+	Points []Coordinate
+	// Attenuation is the loss in dB of links crossing the obstacle, an infinite attenuation blocks them
+	Attenuation float64
+}
+
+// NewRectangleObstacle returns a rectangular obstacle with its lower corner at x, y
+func NewRectangleObstacle(x float64, y float64, width float64, height float64, attenuation float64) Obstacle {
+	return Obstacle{
+		Points:      []Coordinate{{X: x, Y: y}, {X: x + width, Y: y}, {X: x + width, Y: y + height}, {X: x, Y: y + height}},
+		Attenuation: attenuation,
+	}
+}
+
+// Crosses reports whether the line segment from a to b crosses an edge of the obstacle
+func (o *Obstacle) Crosses(a Coordinate, b Coordinate) bool {
+	if len(o.Points) == 0 {
+		return false
+	}
+
+	lower, upper := o.Points[0], o.Points[0]
+	for _, p := range o.Points[1:] {
+		lower = Coordinate{X: min(lower.X, p.X), Y: min(lower.Y, p.Y)}
+		upper = Coordinate{X: max(upper.X, p.X), Y: max(upper.Y, p.Y)}
+	}
+	if max(a.X, b.X) < lower.X || min(a.X, b.X) > upper.X || max(a.Y, b.Y) < lower.Y || min(a.Y, b.Y) > upper.Y {
+		return false
+	}
+
+	for i := range o.Points {
+		if segmentsIntersect(a, b, o.Points[i], o.Points[(i+1)%len(o.Points)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Topography contains the obstacles of the simulated area, which extend through all floors
+type Topography struct {
+	Obstacles []Obstacle
+	// Bounds of the area, it is only used to position the visualizer
+	Bounds Bounds
+}
+
+type Bounds struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Attenuation returns the total loss in dB of the obstacles between a and b
+func (t *Topography) Attenuation(a Coordinate, b Coordinate) float64 {
+	attenuation := 0.0
+	for i := range t.Obstacles {
+		if t.Obstacles[i].Crosses(a, b) {
+			attenuation += t.Obstacles[i].Attenuation
+		}
+	}
+	return attenuation
+}
+
+// segmentsIntersect reports whether the line segments p1-p2 and q1-q2 intersect
+func segmentsIntersect(p1 Coordinate, p2 Coordinate, q1 Coordinate, q2 Coordinate) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation returns the cross product of b-a and c-a, which is positive if c is to the left of the line from a to b
+func orientation(a Coordinate, b Coordinate, c Coordinate) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// onSegment reports whether c, which is collinear with a and b, lies between them
+func onSegment(a Coordinate, b Coordinate, c Coordinate) bool {
+	return min(a.X, b.X) <= c.X && c.X <= max(a.X, b.X) && min(a.Y, b.Y) <= c.Y && c.Y <= max(a.Y, b.Y)
+}
+
+// withinContactRange reports whether the nodes are close enough to connect, taking the floors and obstacles between them into account
+func withinContactRange(nodeRangeSquared float64, topography *Topography, floorAttenuation float64, nodeA *InternalNode, nodeB *InternalNode) bool {
+	distSquared := nodeDistSquared(nodeA, nodeB)
+	rangeSquared := contactRangeSquared(nodeRangeSquared, nodeA, nodeB) * floorFactorSquared(floorAttenuation, nodeA.coords, nodeB.coords)
+	if distSquared >= rangeSquared {
+		return false
+	}
+	return topography == nil || distSquared < rangeSquared*obstacleFactorSquared(topography, nodeA.coords, nodeB.coords)
+}
+
+// obstacleFactorSquared returns the square of the factor by which the obstacles between a and b scale the range
+func obstacleFactorSquared(topography *Topography, a Coordinate, b Coordinate) float64 {
+	return lossFactorSquared(topography.Attenuation(a, b))
+}
@@ -7,12 +7,16 @@ import (
 	"github.com/starling-protocol/simulator"
 )
 
-type BleTransmission struct {
-}
+// BleTransmission drops packets once the path loss exceeds the link budget of BLE.
+// The floor slabs between the nodes add to the path loss, using the floor attenuation of the simulator.
+type BleTransmission struct{}
 
 func (t BleTransmission) Transmission(originCoord simulator.Coordinate, targetCoord simulator.Coordinate, packet []byte) (shouldBeDropped bool, delay time.Duration) {
-	dist := distance(originCoord, targetCoord)
-	pathloss := 40 + 25*math.Log10(dist) // 78
+	return t.TransmissionThroughFloors(originCoord, targetCoord, 0, packet)
+}
+
+func (t BleTransmission) TransmissionThroughFloors(originCoord simulator.Coordinate, targetCoord simulator.Coordinate, floorLoss float64, packet []byte) (shouldBeDropped bool, delay time.Duration) {
+	pathloss := pathLoss(originCoord, targetCoord) + floorLoss // 78
 	if pathloss > 78 {
 		return true, -1
 	} else {
@@ -21,6 +25,33 @@ func (t BleTransmission) Transmission(originCoord simulator.Coordinate, targetCo
 	}
 }
 
+// BleBandwidth approximates the application throughput of a BLE link, which falls back to slower PHYs as the path loss grows.
+// The floor slabs between the nodes add to the path loss, using the floor attenuation of the simulator.
+type BleBandwidth struct{}
+
+func (b BleBandwidth) DataRate(originCoord simulator.Coordinate, targetCoord simulator.Coordinate) float64 {
+	return b.DataRateThroughFloors(originCoord, targetCoord, 0)
+}
+
+func (b BleBandwidth) DataRateThroughFloors(originCoord simulator.Coordinate, targetCoord simulator.Coordinate, floorLoss float64) float64 {
+	pathloss := pathLoss(originCoord, targetCoord) + floorLoss
+	switch {
+	case pathloss <= 60:
+		return 1_400_000 // LE 2M
+	case pathloss <= 70:
+		return 700_000 // LE 1M
+	case pathloss <= 75:
+		return 400_000 // LE Coded S=2
+	default:
+		return 100_000 // LE Coded S=8
+	}
+}
+
+// pathLoss returns the loss in dB over the distance between the coordinates
+func pathLoss(coordA simulator.Coordinate, coordB simulator.Coordinate) float64 {
+	return 40 + 25*math.Log10(distance(coordA, coordB))
+}
+
 func distance(coordA simulator.Coordinate, coordB simulator.Coordinate) float64 {
-	return math.Sqrt(math.Pow(math.Abs(float64(coordA.X)-float64(coordB.X)), 2) + math.Pow(math.Abs(float64(coordA.Y)-float64(coordB.Y)), 2))
+	return math.Sqrt(math.Pow(math.Abs(float64(coordA.X)-float64(coordB.X)), 2) + math.Pow(math.Abs(float64(coordA.Y)-float64(coordB.Y)), 2) + math.Pow(math.Abs(float64(coordA.Z)-float64(coordB.Z)), 2))
 }
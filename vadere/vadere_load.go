@@ -15,6 +15,7 @@ import (
 type VadereNode struct {
 	CoordList []VadereEntry
 	StartTime *time.Duration
+	EndTime   *time.Duration
 }
 
 type VadereEntry struct {
@@ -48,6 +49,7 @@ func VadereLoad(path string, keepRate float64, random *rand.Rand) map[int64]*Vad
 		coordListMap[int64(id)] = &VadereNode{
 			CoordList: []VadereEntry{},
 			StartTime: nil,
+			EndTime:   nil,
 		}
 	}
 
@@ -88,6 +90,10 @@ func VadereLoad(path string, keepRate float64, random *rand.Rand) map[int64]*Vad
 			delay := time.Duration(startTime) * 1_000_000_000
 			coordListMap[int64(id)].StartTime = &delay
 		}
+		leaveTime := time.Duration(endTime * 1_000_000_000)
+		if coordListMap[int64(id)].EndTime == nil || *coordListMap[int64(id)].EndTime < leaveTime {
+			coordListMap[int64(id)].EndTime = &leaveTime
+		}
 	}
 
 	return coordListMap
@@ -1,6 +1,8 @@
 package vadere
 
 import (
+	"bytes"
+	"encoding/gob"
 	"time"
 
 	"github.com/starling-protocol/simulator"
@@ -47,3 +49,15 @@ func (m *MovementProfile) RegisterMovements(coords simulator.Coordinate) simulat
 		Time:   entry.Time,
 	}
 }
+
+// Snapshot implements simulator.Snapshotter.
+func (m *MovementProfile) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(m.coordIndex)
+	return buf.Bytes(), err
+}
+
+// RestoreSnapshot implements simulator.Snapshotter.
+func (m *MovementProfile) RestoreSnapshot(snapshot []byte) error {
+	return gob.NewDecoder(bytes.NewReader(snapshot)).Decode(&m.coordIndex)
+}
@@ -0,0 +1,84 @@
+package vadere
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/starling-protocol/simulator"
+)
+
+const circleSegments = 16
+
+type scenarioFile struct {
+	Scenario struct {
+		Topography struct {
+			Attributes struct {
+				Bounds simulator.Bounds `json:"bounds"`
+			} `json:"attributes"`
+			Obstacles []struct {
+				Shape struct {
+					Type   string  `json:"type"`
+					X      float64 `json:"x"`
+					Y      float64 `json:"y"`
+					Width  float64 `json:"width"`
+					Height float64 `json:"height"`
+					Points []struct {
+						X float64 `json:"x"`
+						Y float64 `json:"y"`
+					} `json:"points"`
+					Center struct {
+						X float64 `json:"x"`
+						Y float64 `json:"y"`
+					} `json:"center"`
+					Radius float64 `json:"radius"`
+				} `json:"shape"`
+			} `json:"obstacles"`
+		} `json:"topography"`
+	} `json:"scenario"`
+}
+
+// LoadTopography reads the obstacles of a Vadere scenario file, which attenuate links crossing them by the given loss in dB.
+// Use math.Inf(1) for obstacles that block links.
+func LoadTopography(path string, attenuation float64) (*simulator.Topography, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario scenarioFile
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	topography := &simulator.Topography{
+		Obstacles: []simulator.Obstacle{},
+		Bounds:    scenario.Scenario.Topography.Attributes.Bounds,
+	}
+	for _, obstacle := range scenario.Scenario.Topography.Obstacles {
+		shape := obstacle.Shape
+		switch shape.Type {
+		case "RECTANGLE":
+			topography.Obstacles = append(topography.Obstacles, simulator.NewRectangleObstacle(shape.X, shape.Y, shape.Width, shape.Height, attenuation))
+		case "POLYGON":
+			points := []simulator.Coordinate{}
+			for _, p := range shape.Points {
+				points = append(points, simulator.Coordinate{X: p.X, Y: p.Y})
+			}
+			topography.Obstacles = append(topography.Obstacles, simulator.Obstacle{Points: points, Attenuation: attenuation})
+		case "CIRCLE":
+			// Circles are approximated by a polygon
+			points := []simulator.Coordinate{}
+			for i := 0; i < circleSegments; i++ {
+				angle := 2 * math.Pi * float64(i) / circleSegments
+				points = append(points, simulator.Coordinate{X: shape.Center.X + shape.Radius*math.Cos(angle), Y: shape.Center.Y + shape.Radius*math.Sin(angle)})
+			}
+			topography.Obstacles = append(topography.Obstacles, simulator.Obstacle{Points: points, Attenuation: attenuation})
+		default:
+			return nil, fmt.Errorf("%s: unsupported obstacle shape %s", path, shape.Type)
+		}
+	}
+
+	return topography, nil
+}
@@ -1,18 +1,16 @@
 package visualizer
 
 import (
-	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
-	"io"
 	"math"
 	"os"
-	"time"
 
 	"github.com/starling-protocol/simulator"
+	"github.com/starling-protocol/simulator/vadere"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -115,8 +113,10 @@ type Visualizer struct {
 	record           bool
 	drawObstacles    bool
 	obstacles        [][]simulator.Coordinate
-	lastUpdate       *time.Time
-	time             time.Duration
+	pacer            *simulator.Pacer
+	// floor is the floor shown in the visualizer, unless allFloors is set
+	floor     int
+	allFloors bool
 }
 
 func NewVisualizer(sim *simulator.Simulator, record bool, speedFactor float64, drawObstacles bool) *Visualizer {
@@ -132,8 +132,9 @@ func NewVisualizer(sim *simulator.Simulator, record bool, speedFactor float64, d
 		record:           record,
 		drawObstacles:    drawObstacles,
 		obstacles:        [][]simulator.Coordinate{},
-		lastUpdate:       nil,
-		time:             0,
+		pacer:            sim.NewPacer(max(0, speedFactor)),
+		floor:            0,
+		allFloors:        true,
 	}
 }
 
@@ -147,23 +148,11 @@ func (v *Visualizer) Update() error {
 
 	v.handleInput()
 
-	if v.s.IsRunning() && v.speedFactor > 0 {
-		// Calculate time
-		now := time.Now()
-		if v.lastUpdate == nil {
-			v.lastUpdate = &now
-		}
-
-		deltaTime := now.Sub(*v.lastUpdate)
-		deltaTime = time.Duration(float64(deltaTime) * v.speedFactor)
-
-		v.time = v.time + deltaTime
-		v.lastUpdate = &now
-
-		v.s.Update(v.time)
-	} else if v.speedFactor <= 0 {
-		now := time.Now()
-		v.lastUpdate = &now
+	if speed := max(0, v.speedFactor); v.pacer.Speed() != speed {
+		v.pacer.SetSpeed(speed)
+	}
+	if v.s.IsRunning() {
+		v.pacer.Update()
 	}
 
 	return nil
@@ -185,12 +174,31 @@ func (v *Visualizer) Draw(screen *ebiten.Image) {
 		v.drawAllObstacles(screen)
 	}
 
+	// Draw the cuts of active partitions in red
+	for _, partition := range v.s.Partitions() {
+		for _, cut := range partition.Cuts {
+			from_x, from_y := v.coordsToPixels(cut.From)
+			to_x, to_y := v.coordsToPixels(cut.To)
+			vector.StrokeLine(screen, float32(from_x), float32(from_y), float32(to_x), float32(to_y), float32(v.zoomFactor)*0.3, color.RGBA{0xdd, 0x22, 0x22, 255}, true)
+		}
+	}
+
 	for _, peer := range v.s.Peers() {
 		// Draw connections
+		shownA, shownB := v.onShownFloor(peer.NodeA()), v.onShownFloor(peer.NodeB())
+		if !shownA && !shownB {
+			continue
+		}
 		var peer_a_x, peer_a_y = v.coordsToPixels(peer.NodeA().Coords())
 		var peer_b_x, peer_b_y = v.coordsToPixels(peer.NodeB().Coords())
 		var midpoint_x, midpoint_y = midpoint(peer_a_x, peer_a_y, peer_b_x, peer_b_y)
 
+		if shownA != shownB {
+			// Connections to other floors are drawn in grey
+			vector.StrokeLine(screen, float32(peer_a_x), float32(peer_a_y), float32(peer_b_x), float32(peer_b_y), float32(v.zoomFactor)*0.1, color.RGBA{0xaa, 0xaa, 0xaa, 255}, true)
+			continue
+		}
+
 		lineStyle := v.getLineStyle(&peer, peer.NodeA())
 		if lineStyle == nil {
 			lineStyle = DefaultLineStyle()
@@ -208,6 +216,9 @@ func (v *Visualizer) Draw(screen *ebiten.Image) {
 
 	var mouseHoverNode *simulator.InternalNode = nil
 	for _, node := range v.s.Nodes() {
+		if !v.onShownFloor(node) {
+			continue
+		}
 		var x, y = v.coordsToPixels(node.Coords())
 		nodeStyle := v.getColor(node)
 		col := color.RGBA{0, 0, 255, 255}
@@ -215,6 +226,10 @@ func (v *Visualizer) Draw(screen *ebiten.Image) {
 			// Draw nodes
 			col = color.RGBA{uint8(nodeStyle.Red / 256), uint8(nodeStyle.Green / 256), uint8(nodeStyle.Blue / 256), 255}
 		}
+		if node.Crashed() {
+			// Draw crashed nodes in grey until they restart
+			col = color.RGBA{128, 128, 128, 255}
+		}
 		radius := 0.5 * float32(v.zoomFactor)
 		vector.DrawFilledCircle(screen, float32(x), float32(y), radius, col, true)
 		if withinDist(mouseX, mouseY, int(x), int(y), float64(radius)) {
@@ -236,7 +251,11 @@ func (v *Visualizer) Draw(screen *ebiten.Image) {
 		}
 	}
 
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("Speed: %f\nZoom: %f", v.speedFactor, v.zoomFactor))
+	floor := "all"
+	if !v.allFloors {
+		floor = fmt.Sprint(v.floor)
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("Speed: %f\nZoom: %f\nFloor: %s\nStalls: %d", v.speedFactor, v.zoomFactor, floor, v.pacer.Stalls()))
 
 	if v.record {
 		out, err := os.Create(fmt.Sprintf("./img/frame-%09d.png", v.frameCount))
@@ -258,6 +277,11 @@ func (v *Visualizer) Draw(screen *ebiten.Image) {
 	v.frameCount++
 }
 
+// onShownFloor reports whether the node is on the floor selected with page up and page down
+func (v *Visualizer) onShownFloor(node *simulator.InternalNode) bool {
+	return v.allFloors || node.Coords().Floor == v.floor
+}
+
 func withinDist(x1 int, y1 int, x2 int, y2 int, dist float64) bool {
 	diffX := math.Abs(float64(x1) - float64(x2))
 	diffY := math.Abs(float64(y1) - float64(y2))
@@ -271,6 +295,7 @@ func (v *Visualizer) Layout(outsideWidth, outsideHeight int) (screenWidth, scree
 // Starts the GUI using the given simulator, with the given speed factor.
 // If record is true, the simulation is recorded
 // If scenarioFilepath is an empty string, no obstacles are rendered
+// Page up and page down select the floor that is shown, and home shows all floors again
 func StartGUI(s *simulator.Simulator, record bool, speedFactor float64, scenarioFilepath string) {
 
 	if record {
@@ -286,69 +311,32 @@ func StartGUI(s *simulator.Simulator, record bool, speedFactor float64, scenario
 		// ffmpeg -framerate 30 -pattern_type glob -i '*.png' -c:v libx264 -pix_fmt yuv420p out.mp4
 	}
 
-	visualizer := NewVisualizer(s, record, speedFactor, scenarioFilepath != "")
-
-	whiteImage.Fill(color.White)
-
+	// The obstacles of the simulator are shown, unless a scenario file is given
+	topography := s.Topography()
 	if scenarioFilepath != "" {
-		scenarioFile, err := os.Open(scenarioFilepath)
+		var err error
+		topography, err = vadere.LoadTopography(scenarioFilepath, math.Inf(1))
 		if err != nil {
 			panic(err)
 		}
+	}
 
-		byteValue, _ := io.ReadAll(scenarioFile)
-
-		var result map[string]interface{}
-		json.Unmarshal([]byte(byteValue), &result)
-
-		obstacleList := [][]simulator.Coordinate{}
-
-		// Get list of obstacles
-		scenario := result["scenario"].(map[string]interface{})
-		topography := scenario["topography"].(map[string]interface{})
-		obstacles := topography["obstacles"].([]interface{})
-		for _, obs := range obstacles {
-			obstaclePoints := []simulator.Coordinate{}
-
-			obstacle := obs.(map[string]interface{})
-			shape := obstacle["shape"].(map[string]interface{})
-			shapeType := shape["type"].(string)
-			switch shapeType {
-			case "RECTANGLE":
-				x := shape["x"].(float64)
-				y := shape["y"].(float64)
-				width := shape["width"].(float64)
-				height := shape["height"].(float64)
-				obstaclePoints = append(obstaclePoints, simulator.Coordinate{X: x, Y: y})
-				obstaclePoints = append(obstaclePoints, simulator.Coordinate{X: x + width, Y: y})
-				obstaclePoints = append(obstaclePoints, simulator.Coordinate{X: x + width, Y: y + height})
-				obstaclePoints = append(obstaclePoints, simulator.Coordinate{X: x, Y: y + height})
-			case "POLYGON":
-				points := shape["points"].([]interface{}) //TODO: Fix so that it does not only work for polygons, but for rectangles as well
-				for _, p := range points {
-					point := p.(map[string]interface{})
-					obstaclePoints = append(obstaclePoints, simulator.Coordinate{X: float64(point["x"].(float64)), Y: float64(point["y"].(float64))})
+	visualizer := NewVisualizer(s, record, speedFactor, topography != nil)
 
-				}
-			}
+	whiteImage.Fill(color.White)
 
-			obstacleList = append(obstacleList, obstaclePoints)
+	if topography != nil {
+		for _, obstacle := range topography.Obstacles {
+			visualizer.obstacles = append(visualizer.obstacles, obstacle.Points)
 		}
 
 		// Find center
-		attributes := topography["attributes"].(map[string]interface{})
-		bounds := attributes["bounds"].(map[string]interface{})
-		x := bounds["x"].(float64)
-		y := bounds["y"].(float64)
-		width := bounds["width"].(float64)
-		height := bounds["height"].(float64)
-		visualizer.offsetX = x - (width / 2)
-		visualizer.offsetY = y + (height / 2)
-		visualizer.zoomFactor = (width - x) / 110
-
-		scenarioFile.Close()
-
-		visualizer.obstacles = obstacleList
+		bounds := topography.Bounds
+		if bounds.Width > 0 && bounds.Height > 0 {
+			visualizer.offsetX = bounds.X - (bounds.Width / 2)
+			visualizer.offsetY = bounds.Y + (bounds.Height / 2)
+			visualizer.zoomFactor = (bounds.Width - bounds.X) / 110
+		}
 	}
 
 	s.Start()
@@ -415,6 +403,19 @@ func (v *Visualizer) handleInput() {
 	if v.speedFactor+0.05*float64(dx) > -0.01 {
 		v.speedFactor += 0.05 * float64(dx)
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		if !v.allFloors {
+			v.floor++
+		}
+		v.allFloors = false
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		if !v.allFloors {
+			v.floor--
+		}
+		v.allFloors = false
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+		v.allFloors = true
+	}
 	if ebiten.IsKeyPressed(ebiten.KeyDigit0) {
 		v.speedFactor = 0
 	} else if ebiten.IsKeyPressed(ebiten.KeyDigit1) {